@@ -0,0 +1,88 @@
+// Package calc sadrži matematiku tiketa (kombinacije, kvote, ulozi i isplate)
+// nezavisno od baze, tako da je servisi samo upisuju.
+package calc
+
+import (
+	"errors"
+	"goticketsistem/models"
+)
+
+var ErrInvalidSystem = errors.New("invalid system combination")
+
+// Leg je jedna selekcija tiketa onako kako ulazi u proračun.
+type Leg struct {
+	Odd    float64
+	Fixed  bool
+	Status string
+}
+
+// Combination je jedna kombinacija; Legs su pozicije selekcija u ulaznoj listi.
+type Combination struct {
+	Legs         []int
+	Odds         float64
+	Stake        float64
+	PotentialWin float64
+}
+
+// Result je proračun tiketa; MinPayout je najmanja isplata među ishodima u kojima
+// tiket nešto dobija, a MaxPayout isplata kada sve selekcije prođu.
+type Result struct {
+	NumCombinations     int
+	StakePerCombination float64
+	TotalOdd            float64
+	MinPayout           float64
+	MaxPayout           float64
+	Combinations        []Combination
+}
+
+// EffectiveOdd vraća kvotu sa kojom selekcija ulazi u proračun kombinacije.
+// Poništena (void) selekcija, bila fiksna ili slobodna, računa se kvotom 1.0.
+func EffectiveOdd(leg Leg) float64 {
+	if leg.Status == models.StatusVoid {
+		return 1.0
+	}
+	return leg.Odd
+}
+
+// CombinationOdds množi efektivne kvote selekcija na datim pozicijama.
+func CombinationOdds(legs []Leg, idx []int) float64 {
+	odds := 1.0
+	for _, i := range idx {
+		odds *= EffectiveOdd(legs[i])
+	}
+	return odds
+}
+
+// Single računa običan tiket: jedna kombinacija sa svim selekcijama i celim ulogom.
+func Single(legs []Leg, totalStake float64) Result {
+	all := make([]int, len(legs))
+	for i := range all {
+		all[i] = i
+	}
+	odds := CombinationOdds(legs, all)
+	potentialWin := odds * totalStake
+	return Result{
+		NumCombinations:     1,
+		StakePerCombination: totalStake,
+		TotalOdd:            odds,
+		MinPayout:           potentialWin, // Normalni tiket dobija samo kada sve prođe
+		MaxPayout:           potentialWin,
+		Combinations:        []Combination{{Legs: all, Odds: odds, Stake: totalStake, PotentialWin: potentialWin}},
+	}
+}
+
+// System računa sistemski tiket: za svaku veličinu k svaka kombinacija sadrži sve
+// fiksne selekcije i još k-f slobodnih. Ulog se deli ravnomerno na sve kombinacije.
+// Vraća i listu svih kombinacija; za velike sisteme koristiti NewSystemPlan.
+func System(legs []Leg, spec SystemSpec, totalStake float64) (Result, error) {
+	plan, err := NewSystemPlan(legs, spec, totalStake)
+	if err != nil {
+		return Result{}, err
+	}
+	res := plan.Summary()
+	res.Combinations = make([]Combination, 0, res.NumCombinations)
+	for c := range plan.Combinations() {
+		res.Combinations = append(res.Combinations, c)
+	}
+	return res, nil
+}
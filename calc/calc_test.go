@@ -0,0 +1,180 @@
+package calc
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"goticketsistem/models"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Abs(b))
+}
+
+func leg(odd float64, status string) Leg {
+	return Leg{Odd: odd, Status: status}
+}
+
+func banker(odd float64, status string) Leg {
+	return Leg{Odd: odd, Fixed: true, Status: status}
+}
+
+func TestEffectiveOdd(t *testing.T) {
+	tests := []struct {
+		leg  Leg
+		want float64
+	}{
+		{leg(2.5, models.StatusPending), 2.5},
+		{leg(2.5, models.StatusWon), 2.5},
+		{leg(2.5, models.StatusVoid), 1},
+		{banker(3.2, models.StatusVoid), 1},
+		{banker(3.2, models.StatusPending), 3.2},
+		{leg(1.8, models.StatusHalfWon), 1.8},
+	}
+	for _, tt := range tests {
+		if got := EffectiveOdd(tt.leg); got != tt.want {
+			t.Errorf("EffectiveOdd(%+v) = %v, want %v", tt.leg, got, tt.want)
+		}
+	}
+}
+
+func TestSingle(t *testing.T) {
+	tests := []struct {
+		name     string
+		legs     []Leg
+		stake    float64
+		totalOdd float64
+		payout   float64
+	}{
+		{"two legs", []Leg{leg(2, models.StatusPending), leg(3, models.StatusPending)}, 10, 6, 60},
+		{"void leg counts as 1.0", []Leg{leg(2, models.StatusPending), leg(3, models.StatusVoid)}, 10, 2, 20},
+		{"all void returns stake", []Leg{leg(1.5, models.StatusVoid), leg(4, models.StatusVoid)}, 10, 1, 10},
+		{"banker is an ordinary leg", []Leg{banker(1.5, models.StatusPending), leg(2, models.StatusPending)}, 4, 3, 12},
+	}
+	for _, tt := range tests {
+		res := Single(tt.legs, tt.stake)
+		if res.NumCombinations != 1 || len(res.Combinations) != 1 {
+			t.Errorf("%s: %d combinations, want 1", tt.name, res.NumCombinations)
+		}
+		if !almostEqual(res.TotalOdd, tt.totalOdd) || !almostEqual(res.MaxPayout, tt.payout) || !almostEqual(res.MinPayout, tt.payout) {
+			t.Errorf("%s: odd %v, payout %v..%v, want odd %v, payout %v", tt.name, res.TotalOdd, res.MinPayout, res.MaxPayout, tt.totalOdd, tt.payout)
+		}
+		if res.StakePerCombination != tt.stake {
+			t.Errorf("%s: stake per combination %v, want %v", tt.name, res.StakePerCombination, tt.stake)
+		}
+	}
+}
+
+func TestSystem(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            string
+		legs            []Leg
+		stake           float64
+		numCombinations int
+		minPayout       float64
+		maxPayout       float64
+	}{
+		{"2/3", "2/3", []Leg{leg(2, models.StatusPending), leg(3, models.StatusPending), leg(4, models.StatusPending)},
+			30, 3, 60, 260},
+		{"2,3/3", "2,3/3", []Leg{leg(2, models.StatusPending), leg(3, models.StatusPending), leg(4, models.StatusPending)},
+			40, 4, 60, 500},
+		{"banker included in k", "3/4+1F", []Leg{banker(2, models.StatusPending), leg(3, models.StatusPending),
+			leg(4, models.StatusPending), leg(5, models.StatusPending)}, 30, 3, 240, 940},
+		{"void free leg", "2/3", []Leg{leg(2, models.StatusPending), leg(3, models.StatusVoid), leg(4, models.StatusPending)},
+			30, 3, 20, 140},
+		{"void banker", "2/3+1F", []Leg{banker(2, models.StatusVoid), leg(3, models.StatusPending), leg(4, models.StatusPending)},
+			30, 2, 45, 105},
+		{"named Trixie", "Trixie", []Leg{leg(2, models.StatusPending), leg(2, models.StatusPending), leg(2, models.StatusPending)},
+			40, 4, 40, 200},
+	}
+	for _, tt := range tests {
+		spec, err := ParseSystem(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: ParseSystem: %v", tt.name, err)
+		}
+		res, err := System(tt.legs, spec, tt.stake)
+		if err != nil {
+			t.Fatalf("%s: System: %v", tt.name, err)
+		}
+		if res.NumCombinations != tt.numCombinations || len(res.Combinations) != tt.numCombinations {
+			t.Errorf("%s: %d combinations (%d listed), want %d", tt.name, res.NumCombinations, len(res.Combinations), tt.numCombinations)
+		}
+		if !almostEqual(res.StakePerCombination, tt.stake/float64(tt.numCombinations)) {
+			t.Errorf("%s: stake per combination %v", tt.name, res.StakePerCombination)
+		}
+		if !almostEqual(res.MinPayout, tt.minPayout) || !almostEqual(res.MaxPayout, tt.maxPayout) {
+			t.Errorf("%s: payout %v..%v, want %v..%v", tt.name, res.MinPayout, res.MaxPayout, tt.minPayout, tt.maxPayout)
+		}
+		var sum float64
+		for _, c := range res.Combinations {
+			sum += c.PotentialWin
+		}
+		if !almostEqual(sum, res.MaxPayout) {
+			t.Errorf("%s: combinations sum to %v, max payout %v", tt.name, sum, res.MaxPayout)
+		}
+	}
+}
+
+func TestSystemInvalid(t *testing.T) {
+	legs := []Leg{leg(2, models.StatusPending), leg(3, models.StatusPending)}
+	for _, s := range []string{"2/3", "3/2+1F", "2/2+1F"} {
+		spec, err := ParseSystem(s)
+		if err == nil {
+			_, err = System(legs, spec, 10)
+		}
+		if !errors.Is(err, ErrInvalidSystem) {
+			t.Errorf("System(%q) error = %v, want ErrInvalidSystem", s, err)
+		}
+	}
+}
+
+func TestSettleCombination(t *testing.T) {
+	tests := []struct {
+		name         string
+		legs         []Leg
+		status       string
+		payout       float64
+		potentialWin float64
+	}{
+		{"all won", []Leg{leg(2, models.StatusWon), leg(3, models.StatusWon)}, models.StatusWon, 60, 60},
+		{"lost beats pending", []Leg{leg(2, models.StatusLost), leg(3, models.StatusPending)}, models.StatusLost, 0, 0},
+		{"pending", []Leg{leg(2, models.StatusWon), leg(3, models.StatusPending)}, models.StatusPending, 0, 60},
+		{"won with void", []Leg{leg(2, models.StatusWon), leg(3, models.StatusVoid)}, models.StatusWon, 20, 20},
+		{"all void", []Leg{leg(2, models.StatusVoid), leg(3, models.StatusVoid)}, models.StatusVoid, 10, 10},
+		{"half won", []Leg{leg(2, models.StatusHalfWon), leg(3, models.StatusWon)}, models.StatusWon, 45, 45},
+		{"half lost", []Leg{leg(2, models.StatusHalfLost), leg(3, models.StatusWon)}, models.StatusWon, 15, 15},
+		{"half lost alone", []Leg{leg(2, models.StatusHalfLost)}, models.StatusWon, 5, 5},
+		{"half won with void", []Leg{leg(3, models.StatusHalfWon), leg(5, models.StatusVoid)}, models.StatusWon, 20, 20},
+		{"half lost and lost", []Leg{leg(2, models.StatusHalfLost), leg(3, models.StatusLost)}, models.StatusLost, 0, 0},
+		{"won banker", []Leg{banker(2, models.StatusWon), leg(1.5, models.StatusWon)}, models.StatusWon, 30, 30},
+	}
+	for _, tt := range tests {
+		status, payout, potentialWin := SettleCombination(tt.legs, 10)
+		if status != tt.status || !almostEqual(payout, tt.payout) || !almostEqual(potentialWin, tt.potentialWin) {
+			t.Errorf("%s: got %s, %v, %v, want %s, %v, %v", tt.name, status, payout, potentialWin, tt.status, tt.payout, tt.potentialWin)
+		}
+	}
+}
+
+func TestTicketStatus(t *testing.T) {
+	tests := []struct {
+		numCombos, pendingCombos, voidCombos int
+		finalPayout                          float64
+		want                                 string
+	}{
+		{3, 1, 0, 0, models.StatusPending},
+		{3, 1, 2, 50, models.StatusPending},
+		{2, 0, 2, 20, models.StatusVoid},
+		{3, 0, 1, 15, models.StatusWon},
+		{3, 0, 0, 0, models.StatusLost},
+		{1, 0, 0, 5, models.StatusWon},
+		{0, 0, 0, 0, models.StatusLost},
+	}
+	for _, tt := range tests {
+		if got := TicketStatus(tt.numCombos, tt.pendingCombos, tt.voidCombos, tt.finalPayout); got != tt.want {
+			t.Errorf("TicketStatus(%d, %d, %d, %v) = %s, want %s", tt.numCombos, tt.pendingCombos, tt.voidCombos, tt.finalPayout, got, tt.want)
+		}
+	}
+}
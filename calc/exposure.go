@@ -0,0 +1,64 @@
+package calc
+
+import "goticketsistem/models"
+
+// LegExposure vraća za svaku selekciju zbir isplata svih kombinacija u kojima se nalazi,
+// tj. koliko se isplaćuje ako ta selekcija prođe, a prođu i sve ostale. Noge na čekanju
+// ulaze svojom kvotom, a obračunate koeficijentom LegFactor, pa kombinacije sa izgubljenom
+// nogom ne doprinose. Fiksna selekcija je u svakoj kombinaciji, a za slobodnu se zbir
+// računa preko e_{k-f-1} nad ostalim slobodnim selekcijama.
+func LegExposure(legs []Leg, spec SystemSpec, stakePerCombination float64) []float64 {
+	factor := func(leg Leg) float64 {
+		if leg.Status == models.StatusPending {
+			return leg.Odd
+		}
+		return LegFactor(leg)
+	}
+
+	fixedFactor := 1.0
+	var fixedCount int
+	var free []int
+	for i, leg := range legs {
+		if leg.Fixed {
+			fixedCount++
+			fixedFactor *= factor(leg)
+		} else {
+			free = append(free, i)
+		}
+	}
+	maxFree := 0
+	for _, k := range spec.Sizes {
+		maxFree = max(maxFree, k-fixedCount)
+	}
+
+	exposure := make([]float64, len(legs))
+	all := make([]float64, len(free))
+	for j, i := range free {
+		all[j] = factor(legs[i])
+	}
+	e := ElementarySymmetric(all, maxFree)
+	var total float64
+	for _, k := range spec.Sizes {
+		total += e[k-fixedCount]
+	}
+	for i, leg := range legs {
+		if leg.Fixed {
+			exposure[i] = stakePerCombination * fixedFactor * total
+		}
+	}
+
+	others := make([]float64, 0, len(free))
+	for j, i := range free {
+		others = append(others[:0], all[:j]...)
+		others = append(others, all[j+1:]...)
+		e := ElementarySymmetric(others, maxFree)
+		var sum float64
+		for _, k := range spec.Sizes {
+			if m := k - fixedCount - 1; m >= 0 {
+				sum += e[m]
+			}
+		}
+		exposure[i] = stakePerCombination * fixedFactor * all[j] * sum
+	}
+	return exposure
+}
@@ -0,0 +1,107 @@
+package calc
+
+import (
+	"goticketsistem/models"
+	"strings"
+)
+
+// NamedBet je imenovani sistem sa punim pokrićem: sve kombinacije veličina
+// MinSize..Selections od Selections selekcija, bez fiksnih.
+type NamedBet struct {
+	Name       string
+	Selections int
+	MinSize    int
+	// OneWinnerMultiplier množi razlomačku kvotu (kvota - 1) jedinog pogodnika kada prođe
+	// tačno jedna selekcija, a sve ostale padnu (npr. 2 za "duple kvote na jedinog
+	// pogodnika"); 0 znači bez bonusa.
+	OneWinnerMultiplier float64
+	// AllWinnersBonus je procenat koji se dodaje isplati kada prođu sve selekcije.
+	AllWinnersBonus float64
+}
+
+var namedBets = []NamedBet{
+	{Name: "Trixie", Selections: 3, MinSize: 2},
+	{Name: "Patent", Selections: 3, MinSize: 1},
+	{Name: "Yankee", Selections: 4, MinSize: 2},
+	{Name: "Lucky 15", Selections: 4, MinSize: 1, OneWinnerMultiplier: 2, AllWinnersBonus: 10},
+	{Name: "Canadian", Selections: 5, MinSize: 2},
+	{Name: "Lucky 31", Selections: 5, MinSize: 1, OneWinnerMultiplier: 2, AllWinnersBonus: 20},
+	{Name: "Heinz", Selections: 6, MinSize: 2},
+	{Name: "Lucky 63", Selections: 6, MinSize: 1, OneWinnerMultiplier: 2, AllWinnersBonus: 25},
+	{Name: "Super Heinz", Selections: 7, MinSize: 2},
+	{Name: "Goliath", Selections: 8, MinSize: 2},
+}
+
+func namedBetKey(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+}
+
+// LookupNamedBet pronalazi imenovani sistem bez obzira na velika slova, razmake i crtice
+// ("Lucky 15", "lucky15", "LUCKY-15"). "Super Yankee" je drugo ime za Canadian.
+func LookupNamedBet(name string) (NamedBet, bool) {
+	key := namedBetKey(name)
+	if key == "superyankee" {
+		key = "canadian"
+	}
+	for _, nb := range namedBets {
+		if namedBetKey(nb.Name) == key {
+			return nb, true
+		}
+	}
+	return NamedBet{}, false
+}
+
+// Spec razvija imenovani sistem u listu veličina kombinacija.
+func (nb NamedBet) Spec() SystemSpec {
+	spec := SystemSpec{N: nb.Selections, FixedGiven: true, Name: nb.Name}
+	for k := nb.MinSize; k <= nb.Selections; k++ {
+		spec.Sizes = append(spec.Sizes, k)
+	}
+	return spec
+}
+
+// SystemBonus primenjuje bonus imenovanog sistema na obračunatu isplatu tiketa.
+// Bonus se dodeljuje samo kada su sve selekcije konačno obračunate kao dobitne ili gubitne.
+// Sa jednim pogodnikom isplaćuje se samo njegov singl, ulog puta kvota, pa je ulog singla
+// finalPayout/kvota, a isplata sa bonusom ulog puta (m·(kvota-1) + 1).
+func SystemBonus(spec SystemSpec, legs []Leg, finalPayout float64) float64 {
+	if spec.Name == "" {
+		return finalPayout
+	}
+	nb, ok := LookupNamedBet(spec.Name)
+	if !ok {
+		return finalPayout
+	}
+	won := 0
+	var winnerOdd float64
+	for _, leg := range legs {
+		switch leg.Status {
+		case models.StatusWon:
+			won++
+			winnerOdd = leg.Odd
+		case models.StatusLost:
+		default:
+			return finalPayout
+		}
+	}
+	switch {
+	case won == 1 && nb.OneWinnerMultiplier > 0 && winnerOdd > 0:
+		return finalPayout / winnerOdd * nb.oneWinnerOdd(winnerOdd)
+	case won == len(legs) && nb.AllWinnersBonus > 0:
+		return finalPayout * nb.allWinnersFactor()
+	}
+	return finalPayout
+}
+
+// oneWinnerOdd vraća decimalnu kvotu jedinog pogodnika sa uvećanom razlomačkom kvotom.
+func (nb NamedBet) oneWinnerOdd(odd float64) float64 {
+	if nb.OneWinnerMultiplier <= 0 {
+		return odd
+	}
+	return nb.OneWinnerMultiplier*(odd-1) + 1
+}
+
+// allWinnersFactor vraća koeficijent isplate kada prođu sve selekcije.
+func (nb NamedBet) allWinnersFactor() float64 {
+	return 1 + nb.AllWinnersBonus/100
+}
@@ -0,0 +1,111 @@
+package calc
+
+import (
+	"slices"
+	"testing"
+
+	"goticketsistem/models"
+)
+
+func TestLookupNamedBet(t *testing.T) {
+	tests := []struct {
+		name      string
+		want      string
+		sizes     []int
+		numCombos int
+	}{
+		{"Trixie", "Trixie", []int{2, 3}, 4},
+		{"patent", "Patent", []int{1, 2, 3}, 7},
+		{"Lucky 15", "Lucky 15", []int{1, 2, 3, 4}, 15},
+		{"lucky15", "Lucky 15", []int{1, 2, 3, 4}, 15},
+		{"LUCKY-31", "Lucky 31", []int{1, 2, 3, 4, 5}, 31},
+		{"Lucky_63", "Lucky 63", []int{1, 2, 3, 4, 5, 6}, 63},
+		{"Super Yankee", "Canadian", []int{2, 3, 4, 5}, 26},
+		{"heinz", "Heinz", []int{2, 3, 4, 5, 6}, 57},
+		{"Super Heinz", "Super Heinz", []int{2, 3, 4, 5, 6, 7}, 120},
+		{"goliath", "Goliath", []int{2, 3, 4, 5, 6, 7, 8}, 247},
+	}
+	for _, tt := range tests {
+		nb, ok := LookupNamedBet(tt.name)
+		if !ok || nb.Name != tt.want {
+			t.Errorf("LookupNamedBet(%q) = %q, %v, want %q", tt.name, nb.Name, ok, tt.want)
+			continue
+		}
+		spec := nb.Spec()
+		if !slices.Equal(spec.Sizes, tt.sizes) || spec.NumCombinations(0) != tt.numCombos {
+			t.Errorf("%s: sizes %v with %d combinations, want %v with %d", tt.name, spec.Sizes, spec.NumCombinations(0), tt.sizes, tt.numCombos)
+		}
+	}
+	for _, name := range []string{"", "Lucky 16", "Yankees", "2/4"} {
+		if _, ok := LookupNamedBet(name); ok {
+			t.Errorf("LookupNamedBet(%q) found a bet", name)
+		}
+	}
+}
+
+func TestSystemBonus(t *testing.T) {
+	won := func(odd float64) Leg { return leg(odd, models.StatusWon) }
+	lost := func(odd float64) Leg { return leg(odd, models.StatusLost) }
+	tests := []struct {
+		name        string
+		spec        string
+		legs        []Leg
+		finalPayout float64
+		want        float64
+	}{
+		// Jedan pogodnik: isplaćuje se samo njegov singl (ulog 1 puta kvota 3), a sa duplom
+		// razlomačkom kvotom 1 * (2*(3-1) + 1) = 5, ne 2 * 3
+		{"Lucky 15 one winner", "Lucky 15", []Leg{lost(2), won(3), lost(4), lost(5)}, 3, 5},
+		{"Lucky 15 one winner, reduced stake", "Lucky 15", []Leg{lost(2), won(3), lost(4), lost(5)}, 1.5, 2.5},
+		{"Lucky 31 one winner", "Lucky 31", []Leg{won(1.5), lost(2), lost(2), lost(2), lost(2)}, 3, 4},
+		{"Lucky 15 all winners", "Lucky 15", []Leg{won(2), won(3), won(4), won(5)}, 359, 359 * 1.1},
+		{"Lucky 31 all winners", "Lucky 31", []Leg{won(2), won(2), won(2), won(2), won(2)}, 100, 120},
+		{"Lucky 63 all winners", "Lucky 63", []Leg{won(2), won(2), won(2), won(2), won(2), won(2)}, 100, 125},
+		{"two winners", "Lucky 15", []Leg{won(2), won(3), lost(4), lost(5)}, 11, 11},
+		{"no winners", "Lucky 15", []Leg{lost(2), lost(3), lost(4), lost(5)}, 0, 0},
+		{"void leg", "Lucky 15", []Leg{won(2), leg(3, models.StatusVoid), won(4), won(5)}, 100, 100},
+		{"pending leg", "Lucky 15", []Leg{won(2), leg(3, models.StatusPending), lost(4), lost(5)}, 2, 2},
+		{"half won leg", "Lucky 15", []Leg{won(2), leg(3, models.StatusHalfWon), won(4), won(5)}, 100, 100},
+		{"named bet without bonus", "Yankee", []Leg{won(2), won(3), won(4), won(5)}, 100, 100},
+		{"plain system", "1-4/4", []Leg{lost(2), won(3), lost(4), lost(5)}, 3, 3},
+	}
+	for _, tt := range tests {
+		spec, err := ParseSystem(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: ParseSystem: %v", tt.name, err)
+		}
+		if got := SystemBonus(spec, tt.legs, tt.finalPayout); !almostEqual(got, tt.want) {
+			t.Errorf("%s: SystemBonus = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummaryIncludesNamedBonus(t *testing.T) {
+	pending := func(odd float64) Leg { return leg(odd, models.StatusPending) }
+	tests := []struct {
+		name      string
+		spec      string
+		legs      []Leg
+		minPayout float64
+		maxPayout float64
+	}{
+		// Ulog 1 po kombinaciji; bez bonusa je raspon 2..359
+		{"Lucky 15", "Lucky 15", []Leg{pending(2), pending(3), pending(4), pending(5)}, 3, 359 * 1.1},
+		{"Lucky 15 with a void leg", "Lucky 15", []Leg{pending(2), leg(3, models.StatusVoid), pending(4), pending(5)}, 1, 3*2*5*6 - 1},
+		{"Yankee", "Yankee", []Leg{pending(2), pending(3), pending(4), pending(5)}, 2 * 3, 359 - 2 - 3 - 4 - 5},
+	}
+	for _, tt := range tests {
+		spec, err := ParseSystem(tt.spec)
+		if err != nil {
+			t.Fatalf("%s: ParseSystem: %v", tt.name, err)
+		}
+		plan, err := NewSystemPlan(tt.legs, spec, float64(spec.NumCombinations(0)))
+		if err != nil {
+			t.Fatalf("%s: NewSystemPlan: %v", tt.name, err)
+		}
+		res := plan.Summary()
+		if !almostEqual(res.MinPayout, tt.minPayout) || !almostEqual(res.MaxPayout, tt.maxPayout) {
+			t.Errorf("%s: payout %v..%v, want %v..%v", tt.name, res.MinPayout, res.MaxPayout, tt.minPayout, tt.maxPayout)
+		}
+	}
+}
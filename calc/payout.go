@@ -0,0 +1,210 @@
+package calc
+
+import (
+	"cmp"
+	"goticketsistem/models"
+	"goticketsistem/utils"
+	"slices"
+)
+
+// Isplate sistema u zatvorenom obliku. Kombinacija veličine k sadrži sve fiksne selekcije
+// i k-f slobodnih, pa je zbir isplata svih takvih kombinacija jednak ulogu po kombinaciji
+// puta proizvod fiksnih kvota puta e_{k-f} nad slobodnim kvotama. Proračun je O(n·k)
+// umesto O(C(n,k)), pa ne zavisi od broja kombinacija.
+
+// fixedProduct vraća proizvod efektivnih kvota fiksnih selekcija.
+func (p *SystemPlan) fixedProduct() float64 {
+	product := 1.0
+	for _, i := range p.fixedIdx {
+		product *= EffectiveOdd(p.legs[i])
+	}
+	return product
+}
+
+// payoutOver sabira isplate svih kombinacija sastavljenih od datih slobodnih kvota.
+func (p *SystemPlan) payoutOver(free []float64) float64 {
+	maxFree := 0
+	for _, k := range p.sizes {
+		maxFree = max(maxFree, k-len(p.fixedIdx))
+	}
+	e := ElementarySymmetric(free, maxFree)
+
+	var total float64
+	for _, k := range p.sizes {
+		if m := k - len(p.fixedIdx); m <= len(free) {
+			total += e[m]
+		}
+	}
+	return p.StakePerCombination * p.fixedProduct() * total
+}
+
+// MaxPayout vraća isplatu kada sve selekcije prođu, tj. zbir mogućih dobitaka svih kombinacija.
+func (p *SystemPlan) MaxPayout() float64 {
+	free := make([]float64, len(p.freeIdx))
+	for j, i := range p.freeIdx {
+		free[j] = EffectiveOdd(p.legs[i])
+	}
+	return p.payoutOver(free)
+}
+
+// MinWinningPayout vraća najmanju isplatu među ishodima u kojima tiket nešto dobija.
+// Isplata ne opada kada prođe još neka selekcija, pa se minimum postiže za najmanji
+// dobitni skup: sve fiksne selekcije, poništene selekcije (koje uvek ulaze kvotom 1.0)
+// i najniže kvote slobodnih selekcija dok se ne popuni najmanja kombinacija. Isplata
+// je zbir svih kombinacija, bilo koje veličine, koje taj skup kompletira.
+func (p *SystemPlan) MinWinningPayout() float64 {
+	winners := slices.Clone(p.fixedIdx)
+	var candidates []int
+	voided := 0
+	for _, i := range p.freeIdx {
+		if p.legs[i].Status == models.StatusVoid {
+			voided++
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+	slices.SortStableFunc(candidates, func(a, b int) int {
+		return cmp.Compare(p.legs[a].Odd, p.legs[b].Odd)
+	})
+
+	need := max(0, slices.Min(p.sizes)-len(p.fixedIdx)-voided)
+	winners = append(winners, candidates[:min(need, len(candidates))]...)
+	return p.PayoutIfWinning(winners)
+}
+
+// PayoutIfWinning vraća isplatu kada prođu tačno selekcije na datim pozicijama, a ostale
+// padnu. Poništene selekcije se ne gube, već uvek ulaze kvotom 1.0. Ako padne bilo koja
+// fiksna selekcija, isplata je 0.
+func (p *SystemPlan) PayoutIfWinning(winners []int) float64 {
+	won := make([]bool, len(p.legs))
+	for _, i := range winners {
+		if i >= 0 && i < len(won) {
+			won[i] = true
+		}
+	}
+	survives := func(i int) bool { return won[i] || p.legs[i].Status == models.StatusVoid }
+
+	for _, i := range p.fixedIdx {
+		if !survives(i) {
+			return 0
+		}
+	}
+	var free []float64
+	for _, i := range p.freeIdx {
+		if survives(i) {
+			free = append(free, EffectiveOdd(p.legs[i]))
+		}
+	}
+	return p.payoutOver(free)
+}
+
+// Summary vraća rezultat sistema (broj kombinacija, ulog po kombinaciji, minimalnu i
+// maksimalnu isplatu) bez generisanja kombinacija. Za imenovane sisteme sa bonusom
+// (Lucky 15/31/63) iznosi uključuju bonus, pa se limit isplate proverava prema onome što
+// se zaista može isplatiti.
+func (p *SystemPlan) Summary() Result {
+	res := Result{
+		NumCombinations:     p.NumCombinations,
+		StakePerCombination: p.StakePerCombination,
+		MinPayout:           p.MinWinningPayout(),
+		MaxPayout:           p.MaxPayout(),
+	}
+	if p.bonusApplies() {
+		res.MaxPayout *= p.named.allWinnersFactor()
+		res.MinPayout = p.minWinningPayoutWithBonus(res.MinPayout)
+	}
+	return res
+}
+
+// bonusApplies javlja da li tiket može dobiti bonus imenovanog sistema: bonus se dodeljuje
+// samo kada su sve selekcije dobitne ili gubitne, pa poništena selekcija isključuje bonus.
+func (p *SystemPlan) bonusApplies() bool {
+	if p.named.OneWinnerMultiplier <= 0 && p.named.AllWinnersBonus <= 0 {
+		return false
+	}
+	for _, leg := range p.legs {
+		if leg.Status == models.StatusVoid {
+			return false
+		}
+	}
+	return true
+}
+
+// minWinningPayoutWithBonus vraća najmanju dobitnu isplatu sa bonusom jednog pogodnika.
+// Sistemi sa tim bonusom sadrže singlove i nemaju fiksnih, pa je najmanji ishod bez bonusa
+// jedan pogodnik sa najnižom kvotom. Bonus uvećava sve ishode sa jednim pogodnikom, pa je
+// minimum manji od tog singla sa bonusom i ishoda sa dva pogodnika najnižih kvota (isplata
+// ne opada kada prođe još neka selekcija).
+func (p *SystemPlan) minWinningPayoutWithBonus(minPayout float64) float64 {
+	if p.named.OneWinnerMultiplier <= 0 || len(p.legs) == 0 {
+		return minPayout
+	}
+	order := make([]int, len(p.legs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(p.legs[a].Odd, p.legs[b].Odd)
+	})
+	payout := p.StakePerCombination * p.named.oneWinnerOdd(p.legs[order[0]].Odd)
+	if len(order) > 1 {
+		payout = min(payout, p.PayoutIfWinning(order[:2]))
+	}
+	return payout
+}
+
+// WinnersPayout je raspon isplate kada prođe tačno Winners slobodnih selekcija (i sve fiksne).
+type WinnersPayout struct {
+	Winners             int
+	WinningCombinations int
+	MinPayout           float64
+	MaxPayout           float64
+}
+
+// PayoutTable vraća isplatu za svaki mogući broj dobitnih slobodnih selekcija, 0..n,
+// uz pretpostavku da sve fiksne selekcije prolaze (inače je isplata 0). Kako e_m raste
+// sa svakom kvotom, najmanju isplatu za j dobitnih daje j najnižih kvota, a najveću j
+// najviših, pa se obe dobijaju inkrementalno nad sortiranim kvotama u O(n·k).
+func (p *SystemPlan) PayoutTable() []WinnersPayout {
+	free := make([]float64, len(p.freeIdx))
+	for j, i := range p.freeIdx {
+		free[j] = EffectiveOdd(p.legs[i])
+	}
+	slices.Sort(free)
+
+	maxFree := 0
+	for _, k := range p.sizes {
+		maxFree = max(maxFree, k-len(p.fixedIdx))
+	}
+	base := p.StakePerCombination * p.fixedProduct()
+	payout := func(e []float64, winners int) float64 {
+		var total float64
+		for _, k := range p.sizes {
+			if m := k - len(p.fixedIdx); m <= winners {
+				total += e[m]
+			}
+		}
+		return base * total
+	}
+
+	n := len(free)
+	table := make([]WinnersPayout, n+1)
+	low := make([]float64, maxFree+1)
+	high := make([]float64, maxFree+1)
+	low[0], high[0] = 1, 1
+	for j := 0; j <= n; j++ {
+		if j > 0 {
+			lowOdd, highOdd := free[j-1], free[n-j]
+			for m := min(j, maxFree); m >= 1; m-- {
+				low[m] += low[m-1] * lowOdd
+				high[m] += high[m-1] * highOdd
+			}
+		}
+		row := WinnersPayout{Winners: j, MinPayout: payout(low, j), MaxPayout: payout(high, j)}
+		for _, k := range p.sizes {
+			row.WinningCombinations += utils.Binom(j, k-len(p.fixedIdx))
+		}
+		table[j] = row
+	}
+	return table
+}
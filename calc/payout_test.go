@@ -0,0 +1,252 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"testing"
+
+	"goticketsistem/models"
+)
+
+// Zatvorene forme iz payout.go i virtual.go se porede sa grubim nabrajanjem svih
+// kombinacija i svih ishoda na malim tiketima.
+
+var payoutOdds = []float64{1.5, 2.1, 3.4, 1.25, 2.8, 4.0}
+
+var payoutSpecs = []string{"1/3", "2/4", "1-4/4", "2,3/5", "3/5+1F", "2-4/6+2F", "4/6+2F", "Yankee"}
+
+// payoutLegs vraća n selekcija na čekanju, od kojih su prve fixed fiksne.
+func payoutLegs(n, fixed int) []Leg {
+	legs := make([]Leg, n)
+	for i := range legs {
+		legs[i] = Leg{Odd: payoutOdds[i], Fixed: i < fixed, Status: models.StatusPending}
+	}
+	return legs
+}
+
+// bruteCombinations vraća sve kombinacije kao bit-maske pozicija: svaka sadrži sve
+// fiksne selekcije i ima jednu od veličina sistema.
+func bruteCombinations(legs []Leg, spec SystemSpec) []uint {
+	var fixedMask uint
+	for i, leg := range legs {
+		if leg.Fixed {
+			fixedMask |= 1 << i
+		}
+	}
+	var combos []uint
+	for mask := uint(0); mask < 1<<len(legs); mask++ {
+		if mask&fixedMask == fixedMask && slices.Contains(spec.Sizes, bits.OnesCount(mask)) {
+			combos = append(combos, mask)
+		}
+	}
+	return combos
+}
+
+func comboLegs(legs []Leg, mask uint) []Leg {
+	var out []Leg
+	for i, leg := range legs {
+		if mask&(1<<i) != 0 {
+			out = append(out, leg)
+		}
+	}
+	return out
+}
+
+// brutePayout sabira isplate kombinacija čije su sve selekcije u skupu survivors.
+func brutePayout(legs []Leg, combos []uint, survivors uint, stake float64) (payout float64, winning int) {
+	for _, mask := range combos {
+		if mask&survivors != mask {
+			continue
+		}
+		odds := 1.0
+		for _, leg := range comboLegs(legs, mask) {
+			odds *= EffectiveOdd(leg)
+		}
+		payout += stake * odds
+		winning++
+	}
+	return payout, winning
+}
+
+func payoutPlan(t *testing.T, s string, legs []Leg) (*SystemPlan, SystemSpec) {
+	t.Helper()
+	spec, err := ParseSystem(s)
+	if err != nil {
+		t.Fatalf("ParseSystem(%q): %v", s, err)
+	}
+	plan, err := NewSystemPlan(legs, spec, 100)
+	if err != nil {
+		t.Fatalf("NewSystemPlan(%q): %v", s, err)
+	}
+	return plan, spec
+}
+
+// forEachPayoutCase poziva f za svaki sistem iz payoutSpecs, sa svim selekcijama na
+// čekanju i sa svakom pojedinačnom selekcijom poništenom.
+func forEachPayoutCase(t *testing.T, f func(name string, legs []Leg, plan *SystemPlan, spec SystemSpec)) {
+	for _, s := range payoutSpecs {
+		spec, err := ParseSystem(s)
+		if err != nil {
+			t.Fatalf("ParseSystem(%q): %v", s, err)
+		}
+		base := payoutLegs(spec.N, spec.Fixed)
+		variants := [][]Leg{base}
+		for i := range base {
+			legs := slices.Clone(base)
+			legs[i].Status = models.StatusVoid
+			variants = append(variants, legs)
+		}
+		for v, legs := range variants {
+			plan, spec := payoutPlan(t, s, legs)
+			f(fmt.Sprintf("%s void #%d", s, v), legs, plan, spec)
+		}
+	}
+}
+
+func TestMaxPayoutBruteForce(t *testing.T) {
+	forEachPayoutCase(t, func(name string, legs []Leg, plan *SystemPlan, spec SystemSpec) {
+		combos := bruteCombinations(legs, spec)
+		if len(combos) != plan.NumCombinations {
+			t.Errorf("%s: %d combinations, brute force %d", name, plan.NumCombinations, len(combos))
+		}
+		want, _ := brutePayout(legs, combos, 1<<len(legs)-1, plan.StakePerCombination)
+		if got := plan.MaxPayout(); !almostEqual(got, want) {
+			t.Errorf("%s: MaxPayout = %v, brute force %v", name, got, want)
+		}
+	})
+}
+
+func TestPayoutIfWinningBruteForce(t *testing.T) {
+	forEachPayoutCase(t, func(name string, legs []Leg, plan *SystemPlan, spec SystemSpec) {
+		combos := bruteCombinations(legs, spec)
+		var voided uint
+		for i, leg := range legs {
+			if leg.Status == models.StatusVoid {
+				voided |= 1 << i
+			}
+		}
+		minWinning := math.Inf(1)
+		for won := uint(0); won < 1<<len(legs); won++ {
+			var winners []int
+			for i := range legs {
+				if won&(1<<i) != 0 {
+					winners = append(winners, i)
+				}
+			}
+			want, _ := brutePayout(legs, combos, won|voided, plan.StakePerCombination)
+			if got := plan.PayoutIfWinning(winners); !almostEqual(got, want) {
+				t.Errorf("%s: PayoutIfWinning(%v) = %v, brute force %v", name, winners, got, want)
+			}
+			if want > 0 {
+				minWinning = min(minWinning, want)
+			}
+		}
+		if got := plan.MinWinningPayout(); !almostEqual(got, minWinning) {
+			t.Errorf("%s: MinWinningPayout = %v, brute force %v", name, got, minWinning)
+		}
+	})
+}
+
+func TestSettleSystemBruteForce(t *testing.T) {
+	statuses := []string{models.StatusPending, models.StatusWon, models.StatusLost,
+		models.StatusVoid, models.StatusHalfWon, models.StatusHalfLost}
+	for _, s := range []string{"2/4", "1-3/4", "2,3/4+1F", "3/5+2F"} {
+		spec, err := ParseSystem(s)
+		if err != nil {
+			t.Fatalf("ParseSystem(%q): %v", s, err)
+		}
+		legs := payoutLegs(spec.N, spec.Fixed)
+		combos := bruteCombinations(legs, spec)
+		const stake = 2.5
+
+		// Svaka selekcija prolazi kroz sve statuse, ukupno 6^n ishoda
+		assignment := make([]int, len(legs))
+		for {
+			for i := range legs {
+				legs[i].Status = statuses[assignment[i]]
+			}
+
+			var want SystemOutcome
+			want.NumCombinations = len(combos)
+			for _, mask := range combos {
+				status, payout, _ := SettleCombination(comboLegs(legs, mask), stake)
+				switch status {
+				case models.StatusPending:
+					want.PendingCombinations++
+				case models.StatusVoid:
+					want.VoidCombinations++
+				}
+				want.Payout += payout
+			}
+			want.Status = TicketStatus(want.NumCombinations, want.PendingCombinations, want.VoidCombinations, want.Payout)
+
+			got := SettleSystem(legs, spec, stake)
+			if got.NumCombinations != want.NumCombinations || got.PendingCombinations != want.PendingCombinations ||
+				got.VoidCombinations != want.VoidCombinations || got.Status != want.Status || !almostEqual(got.Payout, want.Payout) {
+				t.Errorf("%s with %v: SettleSystem = %+v, brute force %+v", s, legStatuses(legs), got, want)
+			}
+
+			i := 0
+			for ; i < len(assignment); i++ {
+				assignment[i]++
+				if assignment[i] < len(statuses) {
+					break
+				}
+				assignment[i] = 0
+			}
+			if i == len(assignment) {
+				break
+			}
+		}
+	}
+}
+
+func legStatuses(legs []Leg) []string {
+	out := make([]string, len(legs))
+	for i, leg := range legs {
+		out[i] = leg.Status
+	}
+	return out
+}
+
+func TestPayoutTableBruteForce(t *testing.T) {
+	for _, s := range payoutSpecs {
+		spec, err := ParseSystem(s)
+		if err != nil {
+			t.Fatalf("ParseSystem(%q): %v", s, err)
+		}
+		legs := payoutLegs(spec.N, spec.Fixed)
+		plan, _ := payoutPlan(t, s, legs)
+		combos := bruteCombinations(legs, spec)
+
+		var fixedMask uint
+		for i := 0; i < spec.Fixed; i++ {
+			fixedMask |= 1 << i
+		}
+		free := len(legs) - spec.Fixed
+		table := plan.PayoutTable()
+		if len(table) != free+1 {
+			t.Fatalf("%s: PayoutTable has %d rows, want %d", s, len(table), free+1)
+		}
+
+		for j := 0; j <= free; j++ {
+			row := WinnersPayout{Winners: j, MinPayout: math.Inf(1), MaxPayout: math.Inf(-1)}
+			for won := uint(0); won < 1<<len(legs); won++ {
+				if won&fixedMask != fixedMask || bits.OnesCount(won)-spec.Fixed != j {
+					continue
+				}
+				payout, winning := brutePayout(legs, combos, won, plan.StakePerCombination)
+				row.WinningCombinations = winning
+				row.MinPayout = min(row.MinPayout, payout)
+				row.MaxPayout = max(row.MaxPayout, payout)
+			}
+			got := table[j]
+			if got.Winners != row.Winners || got.WinningCombinations != row.WinningCombinations ||
+				!almostEqual(got.MinPayout, row.MinPayout) || !almostEqual(got.MaxPayout, row.MaxPayout) {
+				t.Errorf("%s: PayoutTable[%d] = %+v, brute force %+v", s, j, got, row)
+			}
+		}
+	}
+}
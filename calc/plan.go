@@ -0,0 +1,85 @@
+package calc
+
+import (
+	"fmt"
+	"goticketsistem/utils"
+	"iter"
+)
+
+// SystemPlan opisuje sistemski tiket bez generisanja kombinacija; kombinacije se
+// dobijaju lenjo, pa memorija ne zavisi od njihovog broja.
+type SystemPlan struct {
+	legs                []Leg
+	fixedIdx            []int
+	freeIdx             []int
+	sizes               []int
+	named               NamedBet
+	NumCombinations     int
+	StakePerCombination float64
+}
+
+func NewSystemPlan(legs []Leg, spec SystemSpec, totalStake float64) (*SystemPlan, error) {
+	p := &SystemPlan{legs: legs}
+	for i, leg := range legs {
+		if leg.Fixed {
+			p.fixedIdx = append(p.fixedIdx, i)
+		} else {
+			p.freeIdx = append(p.freeIdx, i)
+		}
+	}
+	if err := spec.Validate(len(p.freeIdx), len(p.fixedIdx)); err != nil {
+		return nil, err
+	}
+
+	p.sizes = spec.Sizes
+	if spec.Name != "" {
+		p.named, _ = LookupNamedBet(spec.Name)
+	}
+	p.NumCombinations = spec.NumCombinations(len(p.fixedIdx))
+	if p.NumCombinations == 0 {
+		return nil, fmt.Errorf("%w: no valid combinations calculated", ErrInvalidSystem)
+	}
+	p.StakePerCombination = totalStake / float64(p.NumCombinations)
+	return p, nil
+}
+
+// Combinations lenjo vraća sve kombinacije sistema: po rastućoj veličini, a u okviru
+// iste veličine leksikografski po slobodnim selekcijama.
+func (p *SystemPlan) Combinations() iter.Seq[Combination] {
+	return p.CombinationsFrom(0)
+}
+
+// CombinationsFrom nastavlja generisanje od kombinacije sa datim rangom, gde je rang
+// redni broj kombinacije u redosledu koji vraća Combinations.
+func (p *SystemPlan) CombinationsFrom(rank int) iter.Seq[Combination] {
+	return func(yield func(Combination) bool) {
+		if rank < 0 {
+			return
+		}
+		for _, k := range p.sizes {
+			free := k - len(p.fixedIdx)
+			count := utils.Binom(len(p.freeIdx), free)
+			if rank >= count {
+				rank -= count
+				continue
+			}
+			for comboIdx := range utils.CombinationsFrom(len(p.freeIdx), free, rank) {
+				if !yield(p.combination(comboIdx)) {
+					return
+				}
+			}
+			rank = 0
+		}
+	}
+}
+
+// combination sastavlja kombinaciju od svih fiksnih i datih pozicija među slobodnim selekcijama.
+func (p *SystemPlan) combination(freePositions []int) Combination {
+	legs := make([]int, 0, len(p.fixedIdx)+len(freePositions))
+	legs = append(legs, p.fixedIdx...)
+	for _, pos := range freePositions {
+		legs = append(legs, p.freeIdx[pos])
+	}
+	odds := CombinationOdds(p.legs, legs)
+	return Combination{Legs: legs, Odds: odds, Stake: p.StakePerCombination, PotentialWin: odds * p.StakePerCombination}
+}
@@ -0,0 +1,65 @@
+package calc
+
+import "goticketsistem/models"
+
+// LegFactor vraća koeficijent kojim noga učestvuje u isplati kombinacije.
+// Noga na čekanju ulazi punom kvotom, pa se isti koeficijent koristi i za
+// preostali mogući dobitak kombinacije.
+func LegFactor(leg Leg) float64 {
+	switch leg.Status {
+	case models.StatusWon, models.StatusPending:
+		return leg.Odd
+	case models.StatusVoid:
+		return 1.0
+	case models.StatusHalfWon:
+		return (leg.Odd + 1) / 2
+	case models.StatusHalfLost:
+		return 0.5
+	}
+	return 0
+}
+
+// SettleCombination određuje status, isplatu i preostali mogući dobitak jedne kombinacije
+// na osnovu statusa njenih nogu. Izgubljena noga odmah obara kombinaciju, čak i ako su
+// ostale noge još na čekanju. Kombinacija sastavljena samo od poništenih nogu vraća ulog.
+func SettleCombination(legs []Leg, stake float64) (status string, payout float64, potentialWin float64) {
+	pending := false
+	allVoid := len(legs) > 0
+	factor := 1.0
+	for _, leg := range legs {
+		switch leg.Status {
+		case models.StatusLost:
+			return models.StatusLost, 0, 0
+		case models.StatusPending:
+			pending = true
+		}
+		if leg.Status != models.StatusVoid {
+			allVoid = false
+		}
+		factor *= LegFactor(leg)
+	}
+
+	potentialWin = stake * factor
+	switch {
+	case pending:
+		return models.StatusPending, 0, potentialWin
+	case allVoid:
+		return models.StatusVoid, stake, stake
+	case potentialWin > 0:
+		return models.StatusWon, potentialWin, potentialWin
+	}
+	return models.StatusLost, 0, 0
+}
+
+// TicketStatus određuje status tiketa iz statusa njegovih kombinacija i ukupne isplate.
+func TicketStatus(numCombos, pendingCombos, voidCombos int, finalPayout float64) string {
+	switch {
+	case pendingCombos > 0:
+		return models.StatusPending
+	case numCombos > 0 && voidCombos == numCombos:
+		return models.StatusVoid
+	case finalPayout > 0:
+		return models.StatusWon
+	}
+	return models.StatusLost
+}
@@ -0,0 +1,179 @@
+package calc
+
+import (
+	"fmt"
+	combination_table "goticketsistem/combination"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxSystemSelections je najveći n koji ParseSystem prihvata. Specifikacija dolazi od
+// klijenta pre provere broja selekcija, a za n <= 60 ukupan broj kombinacija (najviše
+// 2^60) staje u int.
+const MaxSystemSelections = 60
+
+// SystemSpec je raščlanjena specifikacija sistema, npr. "2,3/5" ili "3/6+2F".
+// Sistem se tumači kao "k od n uključujući f fiksnih" (isto kao ključ "k/n/f" u
+// combination_table): N je ukupan broj selekcija, Sizes su veličine kombinacija
+// (rastuće, bez ponavljanja) koje uključuju i fiksne ("bankere"), a Fixed je broj
+// fiksnih kada je naveden. Name je ime imenovanog sistema (npr. "Yankee").
+type SystemSpec struct {
+	Sizes      []int
+	N          int
+	Fixed      int
+	FixedGiven bool
+	Name       string
+}
+
+// ParseSystem čita specifikaciju sistema po gramatici:
+//
+//	spec  = sizes "/" n [ "+" f "F" ]
+//	sizes = size { "," size }
+//	size  = k | k "-" k
+//
+// Primeri: "2/4", "2,3/5", "2-4/6", "3/6+2F". Prihvataju se i imenovani sistemi
+// ("Trixie", "Yankee", "Lucky 15", ...).
+func ParseSystem(spec string) (SystemSpec, error) {
+	var s SystemSpec
+	src := strings.ReplaceAll(strings.TrimSpace(spec), " ", "")
+	if src == "" {
+		return s, fmt.Errorf("%w: empty specification", ErrInvalidSystem)
+	}
+	if nb, ok := LookupNamedBet(src); ok {
+		return nb.Spec(), nil
+	}
+
+	sizesPart, rest, ok := strings.Cut(src, "/")
+	if !ok {
+		return s, fmt.Errorf("%w %q: missing \"/n\"", ErrInvalidSystem, spec)
+	}
+
+	nPart, fixedPart, hasFixed := strings.Cut(rest, "+")
+	n, err := parsePositive(nPart)
+	if err != nil {
+		return s, fmt.Errorf("%w %q: bad n %q", ErrInvalidSystem, spec, nPart)
+	}
+	if n > MaxSystemSelections {
+		return s, fmt.Errorf("%w %q: n=%d exceeds the maximum of %d selections", ErrInvalidSystem, spec, n, MaxSystemSelections)
+	}
+	s.N = n
+
+	if hasFixed {
+		f := strings.TrimSuffix(strings.TrimSuffix(fixedPart, "F"), "f")
+		if f == fixedPart {
+			return s, fmt.Errorf("%w %q: fixed count %q must end with F", ErrInvalidSystem, spec, fixedPart)
+		}
+		fixed, err := strconv.Atoi(f)
+		if err != nil || fixed < 0 {
+			return s, fmt.Errorf("%w %q: bad fixed count %q", ErrInvalidSystem, spec, fixedPart)
+		}
+		if fixed > n {
+			return s, fmt.Errorf("%w %q: fixed count %d exceeds n=%d", ErrInvalidSystem, spec, fixed, n)
+		}
+		s.Fixed, s.FixedGiven = fixed, true
+	}
+
+	seen := make(map[int]bool)
+	for _, item := range strings.Split(sizesPart, ",") {
+		lo, hi, err := parseSizeRange(item)
+		if err != nil {
+			return s, fmt.Errorf("%w %q: %v", ErrInvalidSystem, spec, err)
+		}
+		// Granica se proverava pre petlje, pa opseg iz zahteva ne određuje broj koraka
+		if hi > n {
+			return s, fmt.Errorf("%w %q: size %d exceeds n=%d", ErrInvalidSystem, spec, hi, n)
+		}
+		for k := lo; k <= hi; k++ {
+			if k < s.Fixed {
+				return s, fmt.Errorf("%w %q: size %d is smaller than the %d fixed selections", ErrInvalidSystem, spec, k, s.Fixed)
+			}
+			if seen[k] {
+				return s, fmt.Errorf("%w %q: size %d listed more than once", ErrInvalidSystem, spec, k)
+			}
+			seen[k] = true
+			s.Sizes = append(s.Sizes, k)
+		}
+	}
+	slices.Sort(s.Sizes)
+	return s, nil
+}
+
+func parseSizeRange(item string) (int, int, error) {
+	loPart, hiPart, isRange := strings.Cut(item, "-")
+	lo, err := parsePositive(loPart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad size %q", item)
+	}
+	if !isRange {
+		return lo, lo, nil
+	}
+	hi, err := parsePositive(hiPart)
+	if err != nil || hi < lo {
+		return 0, 0, fmt.Errorf("bad size range %q", item)
+	}
+	return lo, hi, nil
+}
+
+func parsePositive(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, fmt.Errorf("value %d must be positive", v)
+	}
+	return v, nil
+}
+
+// Validate proverava specifikaciju prema stvarnom broju slobodnih i fiksnih selekcija tiketa.
+func (s SystemSpec) Validate(free, fixed int) error {
+	if s.N != free+fixed {
+		if s.Name != "" {
+			return fmt.Errorf("%w: %s requires exactly %d selections, ticket has %d", ErrInvalidSystem, s.Name, s.N, free+fixed)
+		}
+		return fmt.Errorf("%w: system is for %d selections, ticket has %d", ErrInvalidSystem, s.N, free+fixed)
+	}
+	if s.FixedGiven && s.Fixed != fixed {
+		return fmt.Errorf("%w: system is for %d fixed selections, ticket has %d", ErrInvalidSystem, s.Fixed, fixed)
+	}
+	if len(s.Sizes) == 0 {
+		return fmt.Errorf("%w: no combination sizes", ErrInvalidSystem)
+	}
+	if s.Sizes[0] < fixed {
+		return fmt.Errorf("%w: size %d is smaller than the %d fixed selections", ErrInvalidSystem, s.Sizes[0], fixed)
+	}
+	return nil
+}
+
+// MinSize vraća najmanju veličinu kombinacije u sistemu.
+func (s SystemSpec) MinSize() int {
+	if len(s.Sizes) == 0 {
+		return 0
+	}
+	return s.Sizes[0]
+}
+
+func (s SystemSpec) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	parts := make([]string, len(s.Sizes))
+	for i, k := range s.Sizes {
+		parts[i] = strconv.Itoa(k)
+	}
+	out := strings.Join(parts, ",") + "/" + strconv.Itoa(s.N)
+	if s.FixedGiven {
+		out += "+" + strconv.Itoa(s.Fixed) + "F"
+	}
+	return out
+}
+
+// NumCombinations vraća ukupan broj kombinacija sistema za dati broj fiksnih selekcija.
+func (s SystemSpec) NumCombinations(fixed int) int {
+	total := 0
+	for _, k := range s.Sizes {
+		total += combination_table.Count(k, s.N, fixed)
+	}
+	return total
+}
@@ -0,0 +1,56 @@
+package calc
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParseSystem(t *testing.T) {
+	tests := []struct {
+		spec   string
+		sizes  []int
+		n      int
+		fixed  int
+		string string
+	}{
+		{"2/4", []int{2}, 4, 0, "2/4"},
+		{"3,2/5", []int{2, 3}, 5, 0, "2,3/5"},
+		{"2-4/6", []int{2, 3, 4}, 6, 0, "2,3,4/6"},
+		{"3/6+2F", []int{3}, 6, 2, "3/6+2F"},
+		{" 4 , 1-2 / 5 ", []int{1, 2, 4}, 5, 0, "1,2,4/5"},
+		{"1-60/60", nil, 60, 0, ""},
+		{"Yankee", []int{2, 3, 4}, 4, 0, "Yankee"},
+	}
+	for _, tt := range tests {
+		s, err := ParseSystem(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSystem(%q): %v", tt.spec, err)
+			continue
+		}
+		if tt.sizes != nil && !slices.Equal(s.Sizes, tt.sizes) {
+			t.Errorf("ParseSystem(%q) sizes = %v, want %v", tt.spec, s.Sizes, tt.sizes)
+		}
+		if s.N != tt.n || s.Fixed != tt.fixed {
+			t.Errorf("ParseSystem(%q) n=%d fixed=%d, want n=%d fixed=%d", tt.spec, s.N, s.Fixed, tt.n, tt.fixed)
+		}
+		if tt.string != "" && s.String() != tt.string {
+			t.Errorf("ParseSystem(%q).String() = %q, want %q", tt.spec, s.String(), tt.string)
+		}
+	}
+}
+
+func TestParseSystemInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"", "2", "2/", "0/3", "4/3", "2,2/4", "3-2/4", "2/4+5F", "2/6+3F", "2/4+1",
+		// Ogromni n i opsezi se odbijaju pre bilo kakve petlje
+		"1/2000000000",
+		"1-50000000/50000000",
+		"1-2000000000/60",
+		"2/61",
+	} {
+		if _, err := ParseSystem(spec); !errors.Is(err, ErrInvalidSystem) {
+			t.Errorf("ParseSystem(%q) error = %v, want ErrInvalidSystem", spec, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package calc
+
+import (
+	"goticketsistem/models"
+	"goticketsistem/utils"
+)
+
+// ElementarySymmetric vraća elementarne simetrične polinome e_0..e_k vrednosti values,
+// gde je e_m zbir proizvoda svih m-članih podskupova. Složenost je O(n·k).
+func ElementarySymmetric(values []float64, k int) []float64 {
+	e := make([]float64, k+1)
+	e[0] = 1
+	for i, v := range values {
+		for m := min(i+1, k); m >= 1; m-- {
+			e[m] += e[m-1] * v
+		}
+	}
+	return e
+}
+
+// SystemOutcome je zbirno stanje sistemskog tiketa izračunato bez pojedinačnih kombinacija.
+type SystemOutcome struct {
+	NumCombinations     int
+	PendingCombinations int
+	VoidCombinations    int
+	Payout              float64
+	Status              string
+}
+
+// SettleSystem obračunava sistem analitički, bez prolaska kroz kombinacije. Kombinacija
+// isplaćuje ulog puta proizvod koeficijenata nogu (LegFactor), pa je zbir isplata svih
+// kombinacija veličine k jednak ulogu puta proizvod koeficijenata fiksnih nogu puta
+// e_{k-f} nad koeficijentima obračunatih slobodnih nogu. Kombinacije sa izgubljenom
+// nogom ne doprinose, a one sa nogom na čekanju se samo prebrojavaju.
+func SettleSystem(legs []Leg, spec SystemSpec, stakePerCombination float64) SystemOutcome {
+	var fixedFactor = 1.0
+	var fixedCount int
+	var fixedLost, fixedPending bool
+	fixedAllVoid := true
+	var settled []float64
+	var pendingFree, voidFree, freeCount int
+	for _, leg := range legs {
+		if leg.Fixed {
+			fixedCount++
+			switch leg.Status {
+			case models.StatusLost:
+				fixedLost = true
+			case models.StatusPending:
+				fixedPending = true
+			}
+			if leg.Status != models.StatusVoid {
+				fixedAllVoid = false
+			}
+			fixedFactor *= LegFactor(leg)
+			continue
+		}
+		freeCount++
+		switch leg.Status {
+		case models.StatusLost:
+		case models.StatusPending:
+			pendingFree++
+		default:
+			if leg.Status == models.StatusVoid {
+				voidFree++
+			}
+			settled = append(settled, LegFactor(leg))
+		}
+	}
+
+	var out SystemOutcome
+	maxFree := 0
+	for _, k := range spec.Sizes {
+		maxFree = max(maxFree, k-fixedCount)
+	}
+	e := ElementarySymmetric(settled, maxFree)
+
+	for _, k := range spec.Sizes {
+		m := k - fixedCount
+		out.NumCombinations += utils.Binom(freeCount, m)
+		if fixedLost {
+			continue
+		}
+		alive := utils.Binom(len(settled)+pendingFree, m)
+		settledAlive := utils.Binom(len(settled), m)
+		if fixedPending {
+			settledAlive = 0
+		} else {
+			out.Payout += stakePerCombination * fixedFactor * e[m]
+		}
+		out.PendingCombinations += alive - settledAlive
+		if fixedAllVoid {
+			out.VoidCombinations += utils.Binom(voidFree, m)
+		}
+	}
+	out.Status = TicketStatus(out.NumCombinations, out.PendingCombinations, out.VoidCombinations, out.Payout)
+	return out
+}
+
+// SystemValue računa zbir vrednosti svih kombinacija sistema bez njihovog generisanja.
+// Obračunate noge ulaze koeficijentom LegFactor, a noge na čekanju koeficijentom koji
+// vraća pendingFactor (npr. odnos originalne i trenutne kvote za isplatu pre kraja).
+// Drugi rezultat je deo vrednosti koji otpada na potpuno obračunate kombinacije.
+func SystemValue(legs []Leg, spec SystemSpec, stakePerCombination float64, pendingFactor func(i int) float64) (float64, float64) {
+	fixedFactor, settledFixedFactor := 1.0, 1.0
+	var fixedCount int
+	var all, settled []float64
+	for i, leg := range legs {
+		factor := LegFactor(leg)
+		if leg.Status == models.StatusPending {
+			factor = pendingFactor(i)
+		}
+		if leg.Fixed {
+			fixedCount++
+			fixedFactor *= factor
+			if leg.Status == models.StatusPending {
+				settledFixedFactor = 0
+			} else {
+				settledFixedFactor *= factor
+			}
+			continue
+		}
+		all = append(all, factor)
+		if leg.Status != models.StatusPending {
+			settled = append(settled, factor)
+		}
+	}
+
+	maxFree := 0
+	for _, k := range spec.Sizes {
+		maxFree = max(maxFree, k-fixedCount)
+	}
+	eAll := ElementarySymmetric(all, maxFree)
+	eSettled := ElementarySymmetric(settled, maxFree)
+
+	var total, settledTotal float64
+	for _, k := range spec.Sizes {
+		m := k - fixedCount
+		total += stakePerCombination * fixedFactor * eAll[m]
+		settledTotal += stakePerCombination * settledFixedFactor * eSettled[m]
+	}
+	return total, settledTotal
+}
@@ -1,6 +1,7 @@
 package combination_table
 
 // Auto-generated combination table (n=1 to 20)
+// Koristi se samo kao očekivane vrednosti za TestCountMatchesTable.
 // Generated in sorted order: n, k, f
 var CombinationTable = map[string]int{
 
@@ -0,0 +1,14 @@
+package combination_table
+
+import "goticketsistem/utils"
+
+// Count vraća broj kombinacija "k od n uključujući f fiksnih": fiksne selekcije ulaze
+// u svaku kombinaciju, pa se bira još k-f od n-f slobodnih, C(n-f, k-f).
+// Za nedozvoljene kombinacije (k < f, k > n, f > n) vraća 0.
+// TestCountMatchesTable proverava da Count daje svaku vrednost ranije tabele (n = 1 do 20).
+func Count(k, n, f int) int {
+	if f < 0 || k < f || k > n || f > n {
+		return 0
+	}
+	return utils.Binom(n-f, k-f)
+}
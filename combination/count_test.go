@@ -0,0 +1,34 @@
+package combination_table
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCountMatchesTable proverava da Count reprodukuje svaki unos tabele "k/n/f".
+func TestCountMatchesTable(t *testing.T) {
+	if len(CombinationTable) == 0 {
+		t.Fatal("combination table is empty")
+	}
+	for key, want := range CombinationTable {
+		var k, n, f int
+		if _, err := fmt.Sscanf(key, "%d/%d/%d", &k, &n, &f); err != nil {
+			t.Fatalf("bad table key %q: %v", key, err)
+		}
+		if got := Count(k, n, f); got != want {
+			t.Errorf("Count(%d, %d, %d) = %d, table has %d", k, n, f, got, want)
+		}
+	}
+}
+
+// TestCountInvalid proverava da nedozvoljene kombinacije, kojih nema u tabeli, daju 0.
+func TestCountInvalid(t *testing.T) {
+	tests := []struct{ k, n, f int }{
+		{2, 3, 3}, {4, 3, 0}, {1, 2, 3}, {2, 4, -1},
+	}
+	for _, tt := range tests {
+		if got := Count(tt.k, tt.n, tt.f); got != 0 {
+			t.Errorf("Count(%d, %d, %d) = %d, want 0", tt.k, tt.n, tt.f, got)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{"key=value", "host=db user=tickets password=s3cret dbname=tickets", "host=db user=tickets password=REDACTED dbname=tickets"},
+		{"key=value with spaces", "user=tickets password = s3cret sslmode=disable", "user=tickets password = REDACTED sslmode=disable"},
+		{"quoted password", `user=tickets password='it\'s a secret' dbname=tickets`, "user=tickets password=REDACTED dbname=tickets"},
+		{"quoted password with spaces", "password='two words' host=db", "password=REDACTED host=db"},
+		{"upper case key", "PASSWORD=s3cret host=db", "PASSWORD=REDACTED host=db"},
+		{"key=value without password", "host=db user=tickets", "host=db user=tickets"},
+		{"URL", "postgres://tickets:s3cret@db:5432/tickets?sslmode=disable", "postgres://tickets:REDACTED@db:5432/tickets?sslmode=disable"},
+		{"URL with password parameter", "postgres://tickets@db/tickets?password=s3cret&sslmode=disable",
+			"postgres://tickets@db/tickets?password=REDACTED&sslmode=disable"},
+		{"URL without password", "postgres://tickets@db/tickets", "postgres://tickets@db/tickets"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactDSN(tt.dsn)
+			if got != tt.want {
+				t.Errorf("redactDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+			}
+			if strings.Contains(got, "s3cret") || strings.Contains(got, "a secret") || strings.Contains(got, "two words") {
+				t.Errorf("redactDSN(%q) = %q leaks the password", tt.dsn, got)
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"TICKETS_DB_DSN":                  "host=db password=s3cret",
+		"TICKETS_DB_MAX_OPEN_CONNS":       "25",
+		"TICKETS_DB_CONN_MAX_LIFETIME":    "90s",
+		"TICKETS_ADMIN_LISTEN_ADDR":       "127.0.0.1:9090",
+		"TICKETS_LIMIT_MAX_TOTAL_STAKE":   "500.5",
+		"TICKETS_LIMIT_CAP_PAYOUT":        "true",
+		"TICKETS_WALLET_DEFAULT_CURRENCY": "RSD",
+		"TICKETS_FEATURE_CASH_OUT":        "false",
+	}
+	cfg := Default()
+	if err := cfg.loadEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}); err != nil {
+		t.Fatalf("loadEnv: %v", err)
+	}
+
+	want := Default()
+	want.Database.DSN = "host=db password=s3cret"
+	want.Database.MaxOpenConns = 25
+	want.Database.ConnMaxLifetime = Duration{90 * time.Second}
+	want.Server.AdminListenAddr = "127.0.0.1:9090"
+	want.Limits.MaxTotalStake = 500.5
+	want.Limits.CapPayout = true
+	want.Wallet.DefaultCurrency = "RSD"
+	want.Features.CashOut = false
+	if cfg != want {
+		t.Errorf("loadEnv result\n%+v\nwant\n%+v", cfg, want)
+	}
+}
+
+func TestLoadEnvInvalid(t *testing.T) {
+	tests := []struct{ name, value string }{
+		{"TICKETS_DB_MAX_OPEN_CONNS", "ten"},
+		{"TICKETS_LIMIT_MAX_PAYOUT", "1e"},
+		{"TICKETS_FEATURE_CASH_OUT", "maybe"},
+		{"TICKETS_CASHOUT_QUOTE_TTL", "30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			err := cfg.loadEnv(func(name string) (string, bool) {
+				return tt.value, name == tt.name
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.name) {
+				t.Errorf("loadEnv(%s=%q) error = %v, want an error naming the variable", tt.name, tt.value, err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Config {
+		cfg := Default()
+		cfg.Database.DSN = "host=db"
+		return cfg
+	}
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   []string
+	}{
+		{"defaults with DSN", func(*Config) {}, nil},
+		{"missing DSN", func(c *Config) { c.Database.DSN = "" }, []string{"database.dsn is required"}},
+		{"idle above open conns", func(c *Config) { c.Database.MaxIdleConns = 20 }, []string{"database.max_idle_conns"}},
+		{"admin on public address", func(c *Config) { c.Server.AdminListenAddr = c.Server.ListenAddr },
+			[]string{"server.admin_listen_addr must differ"}},
+		{"cap without max payout", func(c *Config) { c.Limits.CapPayout, c.Limits.MaxPayout = true, 0 },
+			[]string{"limits.cap_payout requires limits.max_payout"}},
+		{"margin of one", func(c *Config) { c.CashOut.Margin = 1 }, []string{"cash_out.margin"}},
+		{"lower case currency", func(c *Config) { c.Wallet.DefaultCurrency = "eur" }, []string{"wallet.default_currency"}},
+		{"several errors", func(c *Config) {
+			c.Database.DSN = ""
+			c.Server.ReadTimeout = Duration{}
+			c.Features.CombinationBatchSize = 0
+		}, []string{"database.dsn", "server.read_timeout", "features.combination_batch_size"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want errors %q", tt.want)
+			}
+			if got := strings.Count(err.Error(), "\n") + 1; got != len(tt.want) {
+				t.Errorf("Validate() reported %d errors, want %d: %v", got, len(tt.want), err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("Validate() = %v, want it to mention %q", err, w)
+				}
+			}
+		})
+	}
+}
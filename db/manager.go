@@ -13,15 +13,22 @@ type DBManager struct {
 	mux sync.Mutex
 }
 
-func NewDBManager(dsn string) (*DBManager, error) {
+// PoolOptions su podešavanja pula konekcija.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+func NewDBManager(dsn string, pool PoolOptions) (*DBManager, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
 
 	if err := db.Ping(); err != nil {
 		db.Close()
@@ -2,4 +2,7 @@ module goticketsistem
 
 go 1.24.1
 
-require github.com/lib/pq v1.10.9
+require (
+	github.com/lib/pq v1.10.9
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,186 @@
+package grading
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"goticketsistem/models"
+)
+
+func wonOrLost(won bool) string {
+	if won {
+		return models.StatusWon
+	}
+	return models.StatusLost
+}
+
+func normalizeOutcome(outcome string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(outcome), " ", ""))
+}
+
+func matchResult(score Score) string {
+	switch {
+	case score.Home > score.Away:
+		return "1"
+	case score.Home < score.Away:
+		return "2"
+	}
+	return "X"
+}
+
+// Grade1X2 ocenjuje konačan ishod: "1" (domaćin), "X" (nerešeno), "2" (gost).
+func Grade1X2(outcome string, score Score) (string, error) {
+	o := normalizeOutcome(outcome)
+	if o != "1" && o != "X" && o != "2" {
+		return "", fmt.Errorf("%w %q for 1X2", ErrInvalidOutcome, outcome)
+	}
+	return wonOrLost(o == matchResult(score)), nil
+}
+
+// GradeDoubleChance ocenjuje dvostruku šansu: "1X", "12" ili "X2".
+func GradeDoubleChance(outcome string, score Score) (string, error) {
+	o := normalizeOutcome(outcome)
+	switch o {
+	case "1X", "X1":
+		o = "1X"
+	case "12", "21":
+		o = "12"
+	case "X2", "2X":
+		o = "X2"
+	default:
+		return "", fmt.Errorf("%w %q for double chance", ErrInvalidOutcome, outcome)
+	}
+	return wonOrLost(strings.Contains(o, matchResult(score))), nil
+}
+
+// GradeDrawNoBet ocenjuje igru bez remija: "1" ili "2", a nerešen ishod vraća ulog.
+func GradeDrawNoBet(outcome string, score Score) (string, error) {
+	o := normalizeOutcome(outcome)
+	if o != "1" && o != "2" {
+		return "", fmt.Errorf("%w %q for draw no bet", ErrInvalidOutcome, outcome)
+	}
+	actual := matchResult(score)
+	if actual == "X" {
+		return models.StatusVoid, nil
+	}
+	return wonOrLost(o == actual), nil
+}
+
+// GradeBothTeamsToScore ocenjuje "yes"/"no" (ili "GG"/"NG").
+func GradeBothTeamsToScore(outcome string, score Score) (string, error) {
+	both := score.Home > 0 && score.Away > 0
+	switch normalizeOutcome(outcome) {
+	case "YES", "GG":
+		return wonOrLost(both), nil
+	case "NO", "NG":
+		return wonOrLost(!both), nil
+	}
+	return "", fmt.Errorf("%w %q for both teams to score", ErrInvalidOutcome, outcome)
+}
+
+// GradeCorrectScore ocenjuje tačan rezultat u formatu "domaćin-gost".
+func GradeCorrectScore(outcome string, score Score) (string, error) {
+	picked, err := ParseScore(outcome)
+	if err != nil {
+		return "", fmt.Errorf("%w %q for correct score", ErrInvalidOutcome, outcome)
+	}
+	return wonOrLost(picked == score), nil
+}
+
+// GradeOverUnder ocenjuje ukupan broj golova/poena: "over 2.5", "under 2.25", "o3", "u1.75".
+// Azijske linije na četvrtinu dele ulog na dve susedne linije.
+func GradeOverUnder(outcome string, score Score) (string, error) {
+	o := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(outcome), " ", ""))
+	var sign float64
+	switch {
+	case strings.HasPrefix(o, "over"):
+		sign, o = 1, strings.TrimPrefix(o, "over")
+	case strings.HasPrefix(o, "under"):
+		sign, o = -1, strings.TrimPrefix(o, "under")
+	case strings.HasPrefix(o, "o"):
+		sign, o = 1, strings.TrimPrefix(o, "o")
+	case strings.HasPrefix(o, "u"):
+		sign, o = -1, strings.TrimPrefix(o, "u")
+	default:
+		return "", fmt.Errorf("%w %q for over/under", ErrInvalidOutcome, outcome)
+	}
+	line, err := parseLine(o)
+	if err != nil || line < 0 {
+		return "", fmt.Errorf("%w %q for over/under", ErrInvalidOutcome, outcome)
+	}
+	total := float64(score.Total())
+	return gradeAsianLine(line, func(l float64) float64 { return sign * (total - l) }), nil
+}
+
+// GradeHandicap ocenjuje azijski hendikep: "1 -1.5", "2 +0.25", "1 0".
+// Hendikep se dodaje rezultatu izabranog tima.
+func GradeHandicap(outcome string, score Score) (string, error) {
+	fields := strings.Fields(strings.TrimSpace(outcome))
+	if len(fields) != 2 {
+		return "", fmt.Errorf("%w %q for handicap, expected \"<team> <line>\"", ErrInvalidOutcome, outcome)
+	}
+	line, err := parseLine(fields[1])
+	if err != nil {
+		return "", fmt.Errorf("%w %q for handicap", ErrInvalidOutcome, outcome)
+	}
+	var margin float64
+	switch strings.ToUpper(fields[0]) {
+	case "1", "HOME":
+		margin = float64(score.Home - score.Away)
+	case "2", "AWAY":
+		margin = float64(score.Away - score.Home)
+	default:
+		return "", fmt.Errorf("%w %q for handicap", ErrInvalidOutcome, outcome)
+	}
+	return gradeAsianLine(line, func(l float64) float64 { return margin + l }), nil
+}
+
+func parseLine(s string) (float64, error) {
+	line, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.Mod(line*4, 1) != 0 {
+		return 0, fmt.Errorf("line %v is not a multiple of 0.25", line)
+	}
+	return line, nil
+}
+
+// gradeAsianLine ocenjuje liniju preko funkcije razlike: pozitivna razlika je dobitak,
+// negativna gubitak, a nula vraća ulog. Linija na četvrtinu (npr. 2.25) se deli na
+// dve polovine uloga (2.0 i 2.5), pa ishod može biti i polu-dobitak ili polu-gubitak.
+func gradeAsianLine(line float64, margin func(float64) float64) string {
+	if math.Mod(line*2, 1) == 0 {
+		return gradeMargin(margin(line))
+	}
+	return combineHalves(gradeMargin(margin(line-0.25)), gradeMargin(margin(line+0.25)))
+}
+
+func gradeMargin(m float64) string {
+	switch {
+	case m > 0:
+		return models.StatusWon
+	case m < 0:
+		return models.StatusLost
+	}
+	return models.StatusVoid
+}
+
+func combineHalves(a, b string) string {
+	if a == b {
+		return a
+	}
+	if a == models.StatusVoid {
+		a, b = b, a
+	}
+	switch {
+	case a == models.StatusWon && b == models.StatusVoid:
+		return models.StatusHalfWon
+	case a == models.StatusLost && b == models.StatusVoid:
+		return models.StatusHalfLost
+	}
+	// Susedne linije se razlikuju za 0.5 pa dobitak i gubitak ne mogu da se jave zajedno
+	return models.StatusVoid
+}
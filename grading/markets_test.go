@@ -0,0 +1,169 @@
+package grading
+
+import (
+	"errors"
+	"testing"
+
+	"goticketsistem/models"
+)
+
+func TestGradeAsianLine(t *testing.T) {
+	// margin je razlika golova domaćina uvećana za liniju, kao u GradeHandicap
+	tests := []struct {
+		line   float64
+		goals  float64
+		status string
+	}{
+		{0, 1, models.StatusWon},
+		{0, 0, models.StatusVoid},
+		{0, -1, models.StatusLost},
+		{-0.5, 0, models.StatusLost},
+		{-0.5, 1, models.StatusWon},
+		{-1, 1, models.StatusVoid},
+		{0.25, 0, models.StatusHalfWon},
+		{-0.25, 0, models.StatusHalfLost},
+		{-0.75, 1, models.StatusHalfWon},
+		{0.75, -1, models.StatusHalfLost},
+		{-1.25, 1, models.StatusHalfLost},
+		{-1.75, 2, models.StatusHalfWon},
+		{-1.75, 3, models.StatusWon},
+		{-1.75, 1, models.StatusLost},
+	}
+	for _, tt := range tests {
+		got := gradeAsianLine(tt.line, func(l float64) float64 { return tt.goals + l })
+		if got != tt.status {
+			t.Errorf("gradeAsianLine(%v) with margin %v = %s, want %s", tt.line, tt.goals, got, tt.status)
+		}
+	}
+}
+
+func TestCombineHalves(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{models.StatusWon, models.StatusWon, models.StatusWon},
+		{models.StatusLost, models.StatusLost, models.StatusLost},
+		{models.StatusVoid, models.StatusVoid, models.StatusVoid},
+		{models.StatusWon, models.StatusVoid, models.StatusHalfWon},
+		{models.StatusVoid, models.StatusWon, models.StatusHalfWon},
+		{models.StatusLost, models.StatusVoid, models.StatusHalfLost},
+		{models.StatusVoid, models.StatusLost, models.StatusHalfLost},
+	}
+	for _, tt := range tests {
+		if got := combineHalves(tt.a, tt.b); got != tt.want {
+			t.Errorf("combineHalves(%s, %s) = %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGradeHandicap(t *testing.T) {
+	tests := []struct {
+		outcome string
+		score   Score
+		status  string
+	}{
+		{"1 -1.5", Score{2, 0}, models.StatusWon},
+		{"1 -1.5", Score{1, 0}, models.StatusLost},
+		{"2 +1.5", Score{1, 0}, models.StatusWon},
+		{"2 +1.5", Score{2, 0}, models.StatusLost},
+		{"2 1.5", Score{2, 0}, models.StatusLost},
+		{"1 -1", Score{1, 0}, models.StatusVoid},
+		{"home -1", Score{3, 1}, models.StatusWon},
+		{"away +1", Score{1, 0}, models.StatusVoid},
+		{"1 0", Score{0, 0}, models.StatusVoid},
+		{"1 -0.25", Score{0, 0}, models.StatusHalfLost},
+		{"2 +0.25", Score{0, 0}, models.StatusHalfWon},
+		{"2 -0.75", Score{0, 1}, models.StatusHalfWon},
+		{"1 +0.75", Score{0, 1}, models.StatusHalfLost},
+	}
+	for _, tt := range tests {
+		got, err := GradeHandicap(tt.outcome, tt.score)
+		if err != nil {
+			t.Errorf("GradeHandicap(%q, %v) error: %v", tt.outcome, tt.score, err)
+			continue
+		}
+		if got != tt.status {
+			t.Errorf("GradeHandicap(%q, %v) = %s, want %s", tt.outcome, tt.score, got, tt.status)
+		}
+	}
+
+	for _, outcome := range []string{"1", "3 -1", "1 -1.3", "1 abc", "X 0"} {
+		if _, err := GradeHandicap(outcome, Score{1, 0}); !errors.Is(err, ErrInvalidOutcome) {
+			t.Errorf("GradeHandicap(%q) error = %v, want ErrInvalidOutcome", outcome, err)
+		}
+	}
+}
+
+func TestGradeOverUnder(t *testing.T) {
+	tests := []struct {
+		outcome string
+		score   Score
+		status  string
+	}{
+		{"over 2.5", Score{2, 1}, models.StatusWon},
+		{"over 2.5", Score{1, 1}, models.StatusLost},
+		{"under 2.5", Score{1, 1}, models.StatusWon},
+		{"o3", Score{2, 1}, models.StatusVoid},
+		{"over 2.25", Score{1, 1}, models.StatusHalfLost},
+		{"over 2.75", Score{2, 1}, models.StatusHalfWon},
+		{"u1.75", Score{1, 1}, models.StatusHalfLost},
+		{"under 2.25", Score{1, 1}, models.StatusHalfWon},
+	}
+	for _, tt := range tests {
+		got, err := GradeOverUnder(tt.outcome, tt.score)
+		if err != nil {
+			t.Errorf("GradeOverUnder(%q, %v) error: %v", tt.outcome, tt.score, err)
+			continue
+		}
+		if got != tt.status {
+			t.Errorf("GradeOverUnder(%q, %v) = %s, want %s", tt.outcome, tt.score, got, tt.status)
+		}
+	}
+}
+
+func TestGradeDrawNoBet(t *testing.T) {
+	tests := []struct {
+		outcome string
+		score   Score
+		status  string
+	}{
+		{"1", Score{1, 0}, models.StatusWon},
+		{"1", Score{0, 1}, models.StatusLost},
+		{"2", Score{0, 1}, models.StatusWon},
+		{"1", Score{1, 1}, models.StatusVoid},
+		{"2", Score{0, 0}, models.StatusVoid},
+	}
+	for _, tt := range tests {
+		got, err := GradeDrawNoBet(tt.outcome, tt.score)
+		if err != nil {
+			t.Errorf("GradeDrawNoBet(%q, %v) error: %v", tt.outcome, tt.score, err)
+			continue
+		}
+		if got != tt.status {
+			t.Errorf("GradeDrawNoBet(%q, %v) = %s, want %s", tt.outcome, tt.score, got, tt.status)
+		}
+	}
+	if _, err := GradeDrawNoBet("X", Score{1, 1}); !errors.Is(err, ErrInvalidOutcome) {
+		t.Errorf("GradeDrawNoBet(\"X\") error = %v, want ErrInvalidOutcome", err)
+	}
+}
+
+func TestGrade1X2(t *testing.T) {
+	tests := []struct {
+		outcome string
+		score   Score
+		status  string
+	}{
+		{"1", Score{2, 1}, models.StatusWon},
+		{"x", Score{1, 1}, models.StatusWon},
+		{"X", Score{2, 1}, models.StatusLost},
+		{"2", Score{0, 3}, models.StatusWon},
+		{"2", Score{0, 0}, models.StatusLost},
+	}
+	for _, tt := range tests {
+		got, err := Grade1X2(tt.outcome, tt.score)
+		if err != nil || got != tt.status {
+			t.Errorf("Grade1X2(%q, %v) = %s, %v, want %s", tt.outcome, tt.score, got, err, tt.status)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package grading
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Grader određuje status selekcije (won, lost, void, half_won, half_lost)
+// za izabrani ishod i rezultat perioda na koji se igra odnosi.
+type Grader func(outcome string, score Score) (string, error)
+
+// AnySport je ključ pod kojim se registruju pravila koja važe za svaki sport
+// koji nema sopstveno pravilo za dati tip igre.
+const AnySport = "*"
+
+type Registry struct {
+	mu      sync.RWMutex
+	graders map[string]map[string]Grader
+}
+
+func NewRegistry() *Registry {
+	return &Registry{graders: make(map[string]map[string]Grader)}
+}
+
+func normalizeKey(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), " "))
+}
+
+// Register dodaje pravilo za tip igre u okviru sporta. Postojeće pravilo se zamenjuje.
+func (r *Registry) Register(sportType, marketType string, g Grader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sport := normalizeKey(sportType)
+	if r.graders[sport] == nil {
+		r.graders[sport] = make(map[string]Grader)
+	}
+	r.graders[sport][normalizeKey(marketType)] = g
+}
+
+func (r *Registry) lookup(sportType, marketType string) (Grader, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if g, ok := r.graders[normalizeKey(sportType)][marketType]; ok {
+		return g, true
+	}
+	g, ok := r.graders[AnySport][marketType]
+	return g, ok
+}
+
+// Grade ocenjuje selekciju. Tip igre može imati sufiks perioda odvojen dvotačkom
+// (npr. "1X2:1H"), u kom slučaju se koristi rezultat tog perioda umesto konačnog.
+func (r *Registry) Grade(sportType, marketType, outcome string, result Result) (string, error) {
+	market, period, _ := strings.Cut(marketType, ":")
+	g, ok := r.lookup(sportType, normalizeKey(market))
+	if !ok {
+		return "", fmt.Errorf("%w %q for sport %q", ErrUnsupportedMarket, marketType, sportType)
+	}
+
+	score := result.Final
+	if period = normalizeKey(period); period != "" {
+		ps, ok := result.Periods[period]
+		if !ok {
+			return "", fmt.Errorf("%w: missing score for period %s", ErrInvalidResult, period)
+		}
+		score = ps
+	}
+	return g(outcome, score)
+}
+
+// DefaultRegistry vraća registar sa standardnim pravilima za podržane sportove.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	// Pravila zasnovana samo na rezultatu važe za svaki sport
+	register(r, AnySport, "1X2", Grade1X2)
+	register(r, AnySport, "OU", GradeOverUnder, "OVER/UNDER", "TOTAL")
+	register(r, AnySport, "AH", GradeHandicap, "HANDICAP", "ASIAN HANDICAP")
+	register(r, AnySport, "DNB", GradeDrawNoBet, "DRAW NO BET")
+
+	for _, sport := range []string{"football", "soccer", "hockey", "handball"} {
+		register(r, sport, "DC", GradeDoubleChance, "DOUBLE CHANCE")
+		register(r, sport, "BTTS", GradeBothTeamsToScore, "GG/NG", "BOTH TEAMS TO SCORE")
+		register(r, sport, "CS", GradeCorrectScore, "CORRECT SCORE")
+	}
+
+	// U košarci nema nerešenog ishoda, pa se "12" ocenjuje kao igra bez remija
+	register(r, "basketball", "12", GradeDrawNoBet, "MONEYLINE")
+	return r
+}
+
+func register(r *Registry, sportType, marketType string, g Grader, aliases ...string) {
+	r.Register(sportType, marketType, g)
+	for _, alias := range aliases {
+		r.Register(sportType, alias, g)
+	}
+}
@@ -0,0 +1,64 @@
+package grading
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidResult     = errors.New("invalid event result")
+	ErrInvalidOutcome    = errors.New("invalid selected outcome")
+	ErrUnsupportedMarket = errors.New("unsupported market type")
+)
+
+type Score struct {
+	Home int
+	Away int
+}
+
+func (s Score) Total() int {
+	return s.Home + s.Away
+}
+
+// Result je konačan rezultat događaja sa opcionim rezultatima po periodima
+// (npr. "1H", "2H" za poluvremena ili "1Q".."4Q" za četvrtine).
+type Result struct {
+	Final   Score
+	Periods map[string]Score
+}
+
+// ParseScore čita rezultat u formatu "domaćin-gost", npr. "2-1".
+func ParseScore(result string) (Score, error) {
+	parts := strings.Split(strings.TrimSpace(result), "-")
+	if len(parts) != 2 {
+		return Score{}, fmt.Errorf("%w %q, expected format home-away", ErrInvalidResult, result)
+	}
+	home, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || home < 0 {
+		return Score{}, fmt.Errorf("%w %q: bad home score", ErrInvalidResult, result)
+	}
+	away, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || away < 0 {
+		return Score{}, fmt.Errorf("%w %q: bad away score", ErrInvalidResult, result)
+	}
+	return Score{Home: home, Away: away}, nil
+}
+
+// ParseResult čita konačan rezultat i rezultate po periodima.
+func ParseResult(final string, periods map[string]string) (Result, error) {
+	score, err := ParseScore(final)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Final: score, Periods: make(map[string]Score, len(periods))}
+	for period, value := range periods {
+		ps, err := ParseScore(value)
+		if err != nil {
+			return Result{}, fmt.Errorf("period %s: %w", period, err)
+		}
+		res.Periods[strings.ToUpper(strings.TrimSpace(period))] = ps
+	}
+	return res, nil
+}
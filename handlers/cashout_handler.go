@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"goticketsistem/models"
+	"goticketsistem/services"
+)
+
+type CashOutHandler struct {
+	service *services.CashOutService
+}
+
+func NewCashOutHandler(service *services.CashOutService) *CashOutHandler {
+	return &CashOutHandler{service: service}
+}
+
+func (ch *CashOutHandler) HandleQuote(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || ticketID <= 0 {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	quote, err := ch.service.Quote(ticketID)
+	if err != nil {
+		writeCashOutError(w, ticketID, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, quote)
+}
+
+func (ch *CashOutHandler) HandleAccept(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || ticketID <= 0 {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CashOutAcceptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.QuoteID == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	res, err := ch.service.Accept(ticketID, req.QuoteID)
+	if err != nil {
+		writeCashOutError(w, ticketID, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (ch *CashOutHandler) HandlePartial(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || ticketID <= 0 {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.PartialCashOutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.QuoteID == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	res, err := ch.service.AcceptPartial(ticketID, req.QuoteID, req.Percent)
+	if err != nil {
+		writeCashOutError(w, ticketID, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeCashOutError(w http.ResponseWriter, ticketID int, err error) {
+	switch {
+	case errors.Is(err, services.ErrTicketNotFound), errors.Is(err, services.ErrQuoteNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrInvalidLiveOdds), errors.Is(err, services.ErrInvalidCashOutPercent):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrQuoteExpired):
+		http.Error(w, err.Error(), http.StatusGone)
+	case errors.Is(err, services.ErrCashOutUnavailable), errors.Is(err, services.ErrQuoteStale):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error processing cash-out for ticket %d: %v", ticketID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"goticketsistem/db"
+	"goticketsistem/services"
+)
+
+const (
+	defaultLiabilityLimit = 20
+	maxLiabilityLimit     = 500
+)
+
+type LiabilityHandler struct {
+	service *services.LiabilityService
+}
+
+func NewLiabilityHandler(dbManager *db.DBManager) *LiabilityHandler {
+	return &LiabilityHandler{service: services.NewLiabilityService(dbManager)}
+}
+
+// HandleTopExposures vraća ishode sa najvećom izloženošću i najgori slučaj po događaju: ?limit=<broj>.
+func (lh *LiabilityHandler) HandleTopExposures(w http.ResponseWriter, r *http.Request) {
+	limit := defaultLiabilityLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxLiabilityLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := lh.service.TopExposures(limit)
+	if err != nil {
+		log.Printf("Error loading liabilities: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
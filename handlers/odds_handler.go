@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"goticketsistem/models"
+	"goticketsistem/services"
+)
+
+type OddsHandler struct {
+	service *services.LiveOddsService
+}
+
+func NewOddsHandler(service *services.LiveOddsService) *OddsHandler {
+	return &OddsHandler{service: service}
+}
+
+// HandlePublish prima trenutne kvote od servisa kvota; registruje se samo na internom
+// administrativnom serveru.
+func (oh *OddsHandler) HandlePublish(w http.ResponseWriter, r *http.Request) {
+	var req models.PublishOddsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Odds) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	updated, err := oh.service.Publish(req.Odds)
+	if errors.Is(err, services.ErrInvalidLiveOdds) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error publishing live odds: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.PublishOddsResponse{Updated: updated})
+}
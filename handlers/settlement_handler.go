@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"goticketsistem/db"
+	"goticketsistem/grading"
+	"goticketsistem/models"
+	"goticketsistem/services"
+)
+
+type SettlementHandler struct {
+	service *services.SettlementService
+}
+
+func NewSettlementHandler(dbManager *db.DBManager) *SettlementHandler {
+	return &SettlementHandler{service: services.NewSettlementService(dbManager)}
+}
+
+func (sh *SettlementHandler) HandleSettleSelections(w http.ResponseWriter, r *http.Request) {
+	var req models.SettlementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(req.Results) == 0 {
+		http.Error(w, "No results provided", http.StatusBadRequest)
+		return
+	}
+	for _, res := range req.Results {
+		if res.SelectionID <= 0 || !models.IsSettlementStatus(res.Status) {
+			http.Error(w, "Invalid selection result", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ticketIDs, err := sh.service.SettleSelections(req.Results)
+	if errors.Is(err, services.ErrSelectionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error settling selections: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if ticketIDs == nil {
+		ticketIDs = []int{}
+	}
+	writeJSON(w, http.StatusOK, models.SettlementResponse{TicketIDs: ticketIDs})
+}
+
+func (sh *SettlementHandler) HandleSettleEvent(w http.ResponseWriter, r *http.Request) {
+	var event models.EventResult
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if event.Eid == "" || event.MarketType == "" || event.Result == "" {
+		http.Error(w, "Missing eid, market_type or result", http.StatusBadRequest)
+		return
+	}
+
+	res, err := sh.service.SettleEvent(event)
+	if errors.Is(err, grading.ErrInvalidResult) || errors.Is(err, grading.ErrInvalidOutcome) ||
+		errors.Is(err, grading.ErrUnsupportedMarket) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error settling event %s: %v", event.Eid, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
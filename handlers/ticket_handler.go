@@ -2,21 +2,30 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
-	"goticketsistem/db"
+	"goticketsistem/calc"
 	"goticketsistem/models"
 	"goticketsistem/services"
 )
 
+const (
+	defaultCombinationPageSize = 100
+	maxCombinationPageSize     = 1000
+	defaultTicketPageSize      = 50
+	maxTicketPageSize          = 200
+)
+
 type TicketHandler struct {
-	dbManager *db.DBManager
-	service   *services.TicketService
+	service *services.TicketService
 }
 
-func NewTicketHandler(dbManager *db.DBManager) *TicketHandler {
-	return &TicketHandler{dbManager: dbManager, service: services.NewTicketService(dbManager)}
+func NewTicketHandler(service *services.TicketService) *TicketHandler {
+	return &TicketHandler{service: service}
 }
 
 func (th *TicketHandler) HandleTicket(w http.ResponseWriter, r *http.Request) {
@@ -41,23 +50,32 @@ func (th *TicketHandler) HandleTicket(w http.ResponseWriter, r *http.Request) {
 	//     http.Error(w, "Invalid user ID", http.StatusBadRequest)
 	//     return
 	// }
-	if ticket.TotalStake <= 0 {
-		http.Error(w, "Invalid total stake", http.StatusBadRequest)
+	if msg := validateTicket(&ticket); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
-	if len(ticket.Selections) == 0 {
-		http.Error(w, "No selections provided", http.StatusBadRequest)
+	if err := services.ValidateSystem(&ticket); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	for _, sel := range ticket.Selections {
-		if sel.OddValue <= 0 || sel.Stake <= 0 || sel.EventDate.IsZero() {
-			http.Error(w, "Invalid selection data", http.StatusBadRequest)
-			return
-		}
-	}
-
 	ticketID, err := th.service.ProcessTicket(&ticket)
+	var limitErr *services.LimitError
+	if errors.As(err, &limitErr) {
+		writeLimitViolation(w, limitErr)
+		return
+	}
+	var fundsErr *services.FundsError
+	if errors.As(err, &fundsErr) {
+		writeJSON(w, http.StatusUnprocessableEntity, models.InsufficientFunds{
+			Error:    "insufficient_funds",
+			Currency: fundsErr.Currency,
+			Balance:  fundsErr.Balance,
+			Required: fundsErr.Required,
+			Message:  fundsErr.Error(),
+		})
+		return
+	}
 	if err != nil {
 		log.Printf("Error processing ticket: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -71,3 +89,263 @@ func (th *TicketHandler) HandleTicket(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
 }
+
+func (th *TicketHandler) HandleGetTicket(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || ticketID <= 0 {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+
+	details, err := th.service.GetTicket(ticketID)
+	if errors.Is(err, services.ErrTicketNotFound) {
+		http.Error(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading ticket %d: %v", ticketID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, details)
+}
+
+// HandleListCombinations vraća stranicu kombinacija tiketa: ?from=<pozicija>&limit=<broj>.
+func (th *TicketHandler) HandleListCombinations(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || ticketID <= 0 {
+		http.Error(w, "Invalid ticket ID", http.StatusBadRequest)
+		return
+	}
+	from, limit := 0, defaultCombinationPageSize
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil || from < 0 {
+			http.Error(w, "Invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxCombinationPageSize {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := th.service.ListCombinations(ticketID, from, limit)
+	if errors.Is(err, services.ErrTicketNotFound) {
+		http.Error(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error listing combinations for ticket %d: %v", ticketID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// HandleQuoteTicket računa iznose tiketa pre uplate, bez upisa u bazu.
+// Parametar ?combinations=true vraća i listu svih kombinacija.
+func (th *TicketHandler) HandleQuoteTicket(w http.ResponseWriter, r *http.Request) {
+	var ticket models.Ticket
+	if err := json.NewDecoder(r.Body).Decode(&ticket); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if msg := validateTicket(&ticket); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	includeCombinations, _ := strconv.ParseBool(r.URL.Query().Get("combinations"))
+	quote, err := th.service.QuoteTicket(&ticket, includeCombinations)
+	if errors.Is(err, calc.ErrInvalidSystem) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var limitErr *services.LimitError
+	if errors.As(err, &limitErr) {
+		writeLimitViolation(w, limitErr)
+		return
+	}
+	if err != nil {
+		log.Printf("Error quoting ticket: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quote)
+}
+
+// HandlePayoutTable vraća tabelu isplata sistemskog tiketa po broju dobitnih selekcija,
+// bez upisa u bazu.
+func (th *TicketHandler) HandlePayoutTable(w http.ResponseWriter, r *http.Request) {
+	var ticket models.Ticket
+	if err := json.NewDecoder(r.Body).Decode(&ticket); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if msg := validateTicket(&ticket); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	table, err := th.service.PayoutTable(&ticket)
+	if errors.Is(err, calc.ErrInvalidSystem) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error building payout table: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, table)
+}
+
+// writeLimitViolation vraća 422 sa nazivom prekoračenog ograničenja i dozvoljenom vrednošću.
+func writeLimitViolation(w http.ResponseWriter, err *services.LimitError) {
+	writeJSON(w, http.StatusUnprocessableEntity, models.LimitViolation{
+		Error:   "limit_exceeded",
+		Limit:   err.Limit,
+		Value:   err.Value,
+		Allowed: err.Allowed,
+		Message: err.Error(),
+	})
+}
+
+func validateTicket(ticket *models.Ticket) string {
+	if ticket.TotalStake <= 0 {
+		return "Invalid total stake"
+	}
+	if len(ticket.Selections) == 0 {
+		return "No selections provided"
+	}
+	for _, sel := range ticket.Selections {
+		if sel.OddValue <= 0 || sel.Stake <= 0 || sel.EventDate.IsZero() {
+			return "Invalid selection data"
+		}
+	}
+	if ticket.Currency != "" && !services.ValidCurrency(ticket.Currency) {
+		return "Invalid currency"
+	}
+	switch ticket.CombinationStorage {
+	case "", models.StorageRows:
+	case models.StorageVirtual:
+		if ticket.TicketType != "system" || ticket.SystemCombination == "" {
+			return "Virtual combination storage is only available for system tickets"
+		}
+	default:
+		return "Invalid combination storage"
+	}
+	return ""
+}
+
+// HandleListUserTickets vraća tikete jednog korisnika ("Moji tiketi"), sa istim filterima
+// kao HandleListTickets.
+func (th *TicketHandler) HandleListUserTickets(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	filter, msg := parseTicketFilter(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	filter.UserID = userID
+	th.writeTicketPage(w, filter)
+}
+
+// HandleListTickets vraća tikete svih korisnika za back office. Filteri: status, ticket_type,
+// created_from i created_to (RFC3339), min_stake, max_stake, min_payout, max_payout,
+// sport, league, cursor i limit.
+func (th *TicketHandler) HandleListTickets(w http.ResponseWriter, r *http.Request) {
+	filter, msg := parseTicketFilter(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil || userID <= 0 {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		filter.UserID = userID
+	}
+	th.writeTicketPage(w, filter)
+}
+
+func (th *TicketHandler) writeTicketPage(w http.ResponseWriter, filter services.TicketFilter) {
+	page, err := th.service.ListTickets(filter)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error listing tickets: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseTicketFilter čita filtere liste tiketa iz query parametara; vraća poruku o grešci
+// za neispravan parametar.
+func parseTicketFilter(r *http.Request) (services.TicketFilter, string) {
+	q := r.URL.Query()
+	filter := services.TicketFilter{
+		Status:     q.Get("status"),
+		TicketType: q.Get("ticket_type"),
+		Sport:      q.Get("sport"),
+		League:     q.Get("league"),
+		Cursor:     q.Get("cursor"),
+		Limit:      defaultTicketPageSize,
+	}
+	if filter.Status != "" && !models.IsTicketStatus(filter.Status) {
+		return filter, "Invalid status"
+	}
+	if filter.TicketType != "" && filter.TicketType != "normal" && filter.TicketType != "system" {
+		return filter, "Invalid ticket_type"
+	}
+
+	for name, dst := range map[string]*time.Time{"created_from": &filter.CreatedFrom, "created_to": &filter.CreatedTo} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, "Invalid " + name
+			}
+			*dst = t
+		}
+	}
+	for name, dst := range map[string]**float64{
+		"min_stake": &filter.MinStake, "max_stake": &filter.MaxStake,
+		"min_payout": &filter.MinPayout, "max_payout": &filter.MaxPayout,
+	} {
+		if v := q.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				return filter, "Invalid " + name
+			}
+			*dst = &f
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxTicketPageSize {
+			return filter, "Invalid limit"
+		}
+		filter.Limit = limit
+	}
+	return filter, ""
+}
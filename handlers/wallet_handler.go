@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"goticketsistem/models"
+	"goticketsistem/services"
+)
+
+const (
+	defaultStatementPageSize = 50
+	maxStatementPageSize     = 500
+)
+
+type WalletHandler struct {
+	service *services.WalletService
+}
+
+func NewWalletHandler(service *services.WalletService) *WalletHandler {
+	return &WalletHandler{service: service}
+}
+
+// HandleBalances vraća stanje svih računa igrača.
+func (wh *WalletHandler) HandleBalances(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	balances, err := wh.service.Balances(userID)
+	if err != nil {
+		log.Printf("Error loading balances of user %d: %v", userID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, balances)
+}
+
+// HandleStatement vraća izvod računa igrača: ?currency=<valuta>&cursor=<kursor>&limit=<broj>;
+// bez valute se vraća izvod u podrazumevanoj valuti.
+func (wh *WalletHandler) HandleStatement(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	limit := defaultStatementPageSize
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxStatementPageSize {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	statement, err := wh.service.Statement(userID, q.Get("currency"), q.Get("cursor"), limit)
+	if errors.Is(err, services.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidCurrency) {
+		http.Error(w, "Invalid currency", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading statement of user %d: %v", userID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, statement)
+}
+
+// HandleDeposit knjiži uplatu na račun igrača; ponovljena referenca vraća 409. Ruta se
+// registruje samo na internom administrativnom serveru, nikad na javnom.
+func (wh *WalletHandler) HandleDeposit(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || userID <= 0 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.DepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	res, err := wh.service.Deposit(userID, req)
+	switch {
+	case errors.Is(err, services.ErrInvalidAmount), errors.Is(err, services.ErrInvalidCurrency),
+		errors.Is(err, services.ErrMissingReference):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, services.ErrDuplicateTransaction):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Error depositing to user %d: %v", userID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, res)
+}
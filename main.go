@@ -1,26 +1,220 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 
+	"goticketsistem/config"
 	"goticketsistem/db"
 	"goticketsistem/handlers"
+	"goticketsistem/migrations"
+	"goticketsistem/services"
 )
 
 func main() {
-	dsn := "user=postgres password=misa dbname=tickets&system sslmode=disable"
-	dbManager, err := db.NewDBManager(dsn)
+	configPath := flag.String("config", "", "path to a YAML or JSON config file (default $"+config.EnvConfigFile+")")
+	flag.Parse()
+	args := flag.Args()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal("Invalid configuration:\n", err)
+	}
+	// "config" samo ispisuje važeća podešavanja, bez povezivanja na bazu
+	if len(args) > 0 && args[0] == "config" {
+		fmt.Println(cfg)
+		return
+	}
+	log.Printf("Effective configuration:\n%s", cfg)
+
+	dbManager, err := db.NewDBManager(cfg.Database.DSN, db.PoolOptions{
+		MaxOpenConns:    cfg.Database.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.ConnMaxLifetime.Duration,
+	})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 	defer dbManager.Close()
+	ticketService := newTicketService(dbManager, cfg)
+
+	// Jednokratne komande nad postojećim tiketima; bez argumenata se pokreće server
+	if len(args) > 0 {
+		switch args[0] {
+		case "migrate":
+			runMigrate(dbManager, args[1:])
+		case "recompute-payouts":
+			report, err := ticketService.RecomputeMinPayouts()
+			if err != nil {
+				log.Fatal("Failed to recompute payouts:", err)
+			}
+			log.Printf("Updated %d of %d tickets", len(report.Updated), report.Checked)
+			for _, skipped := range report.Skipped {
+				log.Printf("Skipped ticket %d: %s", skipped.TicketID, skipped.Error)
+			}
+		case "reconcile-tickets":
+			// --dry-run samo ispisuje polu-upisane tikete
+			dryRun := len(args) > 1 && args[1] == "--dry-run"
+			report, err := ticketService.ReconcileTickets(dryRun)
+			if err != nil {
+				log.Fatal("Failed to reconcile tickets:", err)
+			}
+			log.Printf("Half-written tickets: %v, repaired: %v, removed: %v", report.Found, report.Repaired, report.Removed)
+		default:
+			log.Fatalf("Unknown command %q", args[0])
+		}
+		return
+	}
 
-	handler := handlers.NewTicketHandler(dbManager)
-	mux := http.NewServeMux()                       // Kreiraj novi ServeMux
+	if cfg.Features.MigrateOnStart {
+		if _, err := migrations.Up(dbManager.GetDB()); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+	}
+
+	handler := handlers.NewTicketHandler(ticketService)
+	mux := http.NewServeMux() // Kreiraj novi ServeMux
+	// Rute bez autentifikacije koje pomeraju novac (obračun, uplate) ili otkrivaju podatke
+	// svih igrača (back-office pregledi) nude se samo na internom serveru, nikad javno
+	adminMux := http.NewServeMux()
 	mux.HandleFunc("/ticket", handler.HandleTicket) // Registrovani handler
-	log.Println("Server starting on :8080 at 02:30 PM CEST, June 07, 2025...")
-	if err := http.ListenAndServe(":8080", mux); err != nil { // Koristi mux
+	mux.HandleFunc("GET /ticket/{id}", handler.HandleGetTicket)
+	mux.HandleFunc("POST /ticket/quote", handler.HandleQuoteTicket)
+	mux.HandleFunc("POST /ticket/quote/payouts", handler.HandlePayoutTable)
+	mux.HandleFunc("GET /ticket/{id}/combinations", handler.HandleListCombinations)
+	mux.HandleFunc("GET /users/{id}/tickets", handler.HandleListUserTickets)
+	adminMux.HandleFunc("GET /tickets", handler.HandleListTickets)
+
+	settlementHandler := handlers.NewSettlementHandler(dbManager)
+	adminMux.HandleFunc("POST /settlement", settlementHandler.HandleSettleSelections)
+	adminMux.HandleFunc("POST /settlement/event", settlementHandler.HandleSettleEvent)
+
+	if cfg.Features.CashOut {
+		cashOutService := services.NewCashOutService(dbManager, cfg.CashOut.Margin, cfg.CashOut.QuoteTTL.Duration)
+		cashOutService.SetMaxOddsAge(cfg.CashOut.MaxOddsAge.Duration)
+		cashOutHandler := handlers.NewCashOutHandler(cashOutService)
+		mux.HandleFunc("POST /ticket/{id}/cashout/quote", cashOutHandler.HandleQuote)
+		mux.HandleFunc("POST /ticket/{id}/cashout/accept", cashOutHandler.HandleAccept)
+		mux.HandleFunc("POST /ticket/{id}/cashout/partial", cashOutHandler.HandlePartial)
+	}
+
+	// Trenutne kvote za isplatu pre kraja objavljuje samo servis kvota
+	oddsHandler := handlers.NewOddsHandler(services.NewLiveOddsService(dbManager))
+	adminMux.HandleFunc("PUT /odds", oddsHandler.HandlePublish)
+
+	liabilityHandler := handlers.NewLiabilityHandler(dbManager)
+	adminMux.HandleFunc("GET /liabilities", liabilityHandler.HandleTopExposures)
+
+	walletService := services.NewWalletService(dbManager)
+	walletService.SetDefaultCurrency(cfg.Wallet.DefaultCurrency)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	mux.HandleFunc("GET /users/{id}/wallet", walletHandler.HandleBalances)
+	mux.HandleFunc("GET /users/{id}/wallet/statement", walletHandler.HandleStatement)
+	adminMux.HandleFunc("POST /users/{id}/wallet/deposit", walletHandler.HandleDeposit)
+
+	if cfg.Server.AdminListenAddr == "" {
+		log.Printf("Admin listener disabled: settlement, deposits, /tickets and /liabilities are not served")
+	} else {
+		adminServer := newServer(cfg.Server, cfg.Server.AdminListenAddr, adminMux)
+		go func() {
+			log.Printf("Admin server starting on %s...", cfg.Server.AdminListenAddr)
+			if err := adminServer.ListenAndServe(); err != nil {
+				log.Fatal("Admin server failed:", err)
+			}
+		}()
+	}
+
+	server := newServer(cfg.Server, cfg.Server.ListenAddr, mux)
+	log.Printf("Server starting on %s...", cfg.Server.ListenAddr)
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatal("Server failed:", err)
 	}
 }
+
+// newServer pravi HTTP server na datoj adresi sa vremenskim ograničenjima iz podešavanja.
+func newServer(cfg config.ServerConfig, addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout.Duration,
+		WriteTimeout: cfg.WriteTimeout.Duration,
+		IdleTimeout:  cfg.IdleTimeout.Duration,
+	}
+}
+
+// newTicketService pravi servis tiketa sa limitima, načinom upisa kombinacija i
+// podrazumevanom valutom iz podešavanja.
+func newTicketService(dbManager *db.DBManager, cfg config.Config) *services.TicketService {
+	ts := services.NewTicketService(dbManager)
+	ts.SetPlacementLimits(services.PlacementLimits{
+		MinStakePerCombination: cfg.Limits.MinStakePerCombination,
+		MaxTotalStake:          cfg.Limits.MaxTotalStake,
+		MaxPayout:              cfg.Limits.MaxPayout,
+		CapPayout:              cfg.Limits.CapPayout,
+		MaxSelections:          cfg.Limits.MaxSelections,
+		MaxCombinations:        cfg.Limits.MaxCombinations,
+		MaxOutcomeLiability:    cfg.Limits.MaxOutcomeLiability,
+		MaxQuoteCombinations:   cfg.Limits.MaxQuoteCombinations,
+	})
+	ts.SetCombinationWriteOptions(services.CombinationWriteOptions{
+		BatchSize: cfg.Features.CombinationBatchSize,
+		Copy:      cfg.Features.CopyCombinations,
+	})
+	ts.SetDefaultCurrency(cfg.Wallet.DefaultCurrency)
+	return ts
+}
+
+// runMigrate izvršava "migrate up", "migrate down [broj koraka]" ili "migrate status".
+func runMigrate(dbManager *db.DBManager, args []string) {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		applied, err := migrations.Up(dbManager.GetDB())
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		log.Printf("Applied migrations: %v", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				log.Fatalf("Invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(dbManager.GetDB(), steps)
+		if err != nil {
+			log.Fatal("Failed to revert migrations:", err)
+		}
+		log.Printf("Reverted migrations: %v", reverted)
+	case "status":
+		all, err := migrations.Load()
+		if err != nil {
+			log.Fatal("Failed to load migrations:", err)
+		}
+		applied, err := migrations.Applied(dbManager.GetDB())
+		if err != nil {
+			log.Fatal("Failed to read migration status:", err)
+		}
+		done := map[int]bool{}
+		for _, v := range applied {
+			done[v] = true
+		}
+		for _, m := range all {
+			state := "pending"
+			if done[m.Version] {
+				state = "applied"
+			}
+			log.Printf("%04d_%s: %s", m.Version, m.Name, state)
+		}
+	default:
+		log.Fatalf("Unknown migrate command %q, expected up, down or status", command)
+	}
+}
@@ -0,0 +1,217 @@
+// Package migrations sadrži šemu baze kao numerisane SQL migracije ugrađene u binarni
+// fajl. Migracija N se sastoji od fajlova "N_naziv.up.sql" i "N_naziv.down.sql", a
+// primenjene verzije se beleže u tabeli schema_migrations.
+package migrations
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID je ključ advisory lock-a, da dve instance servisa ne bi migrirale istovremeno.
+const lockID = 7203114
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load čita ugrađene migracije sortirane po verziji i proverava da svaka ima oba smera.
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: expected <version>_<name>.up.sql or .down.sql", name)
+		}
+		num, label, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", name, num)
+		}
+		body, err := files.ReadFile("sql/" + name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s is missing its up or down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up primenjuje sve migracije koje još nisu primenjene i vraća njihove verzije.
+// Svaka migracija se izvršava u svojoj transakciji zajedno sa upisom u schema_migrations.
+func Up(db *sql.DB) ([]int, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, m := range migrations {
+		done, err := apply(db, m.Version, func(tx *sql.Tx, isApplied bool) (bool, error) {
+			if isApplied {
+				return false, nil
+			}
+			if _, err := tx.Exec(m.Up); err != nil {
+				return false, err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+			return true, err
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s up: %v", m.Version, m.Name, err)
+		}
+		if done {
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+			applied = append(applied, m.Version)
+		}
+	}
+	return applied, nil
+}
+
+// Down poništava poslednjih steps primenjenih migracija, od najnovije, i vraća njihove verzije.
+func Down(db *sql.DB, steps int) ([]int, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	current, err := Applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]Migration{}
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+	var reverted []int
+	for i := len(current) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m, ok := byVersion[current[i]]
+		if !ok {
+			return reverted, fmt.Errorf("applied migration %d is not known to this build", current[i])
+		}
+		done, err := apply(db, m.Version, func(tx *sql.Tx, isApplied bool) (bool, error) {
+			if !isApplied {
+				return false, nil
+			}
+			if _, err := tx.Exec(m.Down); err != nil {
+				return false, err
+			}
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+			return true, err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("migration %d_%s down: %v", m.Version, m.Name, err)
+		}
+		if done {
+			log.Printf("Reverted migration %d_%s", m.Version, m.Name)
+			reverted = append(reverted, m.Version)
+		}
+	}
+	return reverted, nil
+}
+
+// Applied vraća verzije primenjenih migracija u rastućem redosledu.
+func Applied(db *sql.DB) ([]int, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %v", err)
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func ensureTable(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+             version    INTEGER PRIMARY KEY,
+             name       TEXT NOT NULL,
+             applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+         )`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// apply izvršava step u transakciji pod advisory lock-om. Stanje verzije se čita tek
+// posle zaključavanja, pa paralelno pokretanje ne primenjuje istu migraciju dvaput.
+func apply(db *sql.DB, version int, step func(tx *sql.Tx, isApplied bool) (bool, error)) (bool, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	var isApplied bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&isApplied); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	done, err := step(tx, isApplied)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if !done {
+		tx.Rollback()
+		return false, nil
+	}
+	return true, tx.Commit()
+}
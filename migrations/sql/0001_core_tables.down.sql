@@ -0,0 +1,14 @@
+-- Tabele koje je 0001 samo preuzela iz ručno napravljene baze se ne brišu: poništavanje
+-- se prekida, a brisanje je odluka koja se donosi ručno.
+DO $$
+BEGIN
+    IF to_regclass('schema_adopted') IS NOT NULL AND EXISTS (SELECT 1 FROM schema_adopted WHERE version = 1) THEN
+        RAISE EXCEPTION 'tickets, selections and combinations existed before migration 0001 and were only adopted by it; '
+            'drop them by hand and delete version 1 from schema_adopted to revert it';
+    END IF;
+END $$;
+
+DROP TABLE IF EXISTS combinations;
+DROP TABLE IF EXISTS selections;
+DROP TABLE IF EXISTS tickets;
+DROP TABLE IF EXISTS schema_adopted;
@@ -0,0 +1,149 @@
+-- Baze napravljene ručno pre migracija već imaju ove tabele, pa ih 0001 preuzima:
+-- postojeće tabele i indeksi ostaju, dodaju se kolone uvedene posle ručne šeme, a
+-- ograničenja (NOT NULL, CHECK, spoljni ključevi) se postavljaju i proveravaju nad
+-- postojećim redovima. Red koji ih krši prekida migraciju greškom koja navodi ograničenje.
+-- Preuzimanje se beleži u schema_adopted, pa poništavanje 0001 ne briše te tabele.
+CREATE TABLE IF NOT EXISTS schema_adopted (
+    version    INTEGER PRIMARY KEY,
+    adopted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+INSERT INTO schema_adopted (version)
+    SELECT 1 WHERE to_regclass('tickets') IS NOT NULL
+    ON CONFLICT (version) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS tickets (
+    ticket_id           SERIAL PRIMARY KEY,
+    user_id             INTEGER NOT NULL,
+    total_stake         NUMERIC NOT NULL CHECK (total_stake > 0),
+    total_odd           NUMERIC NOT NULL DEFAULT 0,
+    potential_payout    NUMERIC NOT NULL DEFAULT 0,
+    hits                INTEGER NOT NULL DEFAULT 0,
+    misses              INTEGER NOT NULL DEFAULT 0,
+    pending             INTEGER NOT NULL DEFAULT 0,
+    status              TEXT NOT NULL DEFAULT 'pending'
+                        CHECK (status IN ('pending', 'won', 'lost', 'void', 'cashed_out')),
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+    max_payout          NUMERIC NOT NULL DEFAULT 0,
+    min_payout          NUMERIC NOT NULL DEFAULT 0,
+    final_payout        NUMERIC NOT NULL DEFAULT 0,
+    num_combinations    BIGINT NOT NULL DEFAULT 0,
+    system_combination  TEXT,
+    ticket_type         TEXT NOT NULL DEFAULT 'normal',
+    combination_storage TEXT NOT NULL DEFAULT 'rows' CHECK (combination_storage IN ('rows', 'virtual')),
+    payout_cap          NUMERIC CHECK (payout_cap > 0)
+);
+
+CREATE INDEX IF NOT EXISTS tickets_status_idx ON tickets (status);
+CREATE INDEX IF NOT EXISTS tickets_user_created_idx ON tickets (user_id, created_at DESC, ticket_id DESC);
+CREATE INDEX IF NOT EXISTS tickets_created_idx ON tickets (created_at DESC, ticket_id DESC);
+
+CREATE TABLE IF NOT EXISTS selections (
+    selection_id     SERIAL PRIMARY KEY,
+    ticket_id        INTEGER NOT NULL REFERENCES tickets (ticket_id) ON DELETE CASCADE,
+    sport_type       TEXT NOT NULL DEFAULT '',
+    league           TEXT NOT NULL DEFAULT '',
+    home_team        TEXT NOT NULL DEFAULT '',
+    away_team        TEXT NOT NULL DEFAULT '',
+    event_date       TIMESTAMPTZ NOT NULL,
+    market_type      TEXT NOT NULL DEFAULT '',
+    selected_outcome TEXT NOT NULL DEFAULT '',
+    odd_value        NUMERIC NOT NULL CHECK (odd_value > 0),
+    stake            NUMERIC NOT NULL DEFAULT 0,
+    eid              TEXT NOT NULL DEFAULT '',
+    selection_type   TEXT NOT NULL DEFAULT '',
+    status           TEXT NOT NULL DEFAULT 'pending'
+                     CHECK (status IN ('pending', 'won', 'lost', 'void', 'half_won', 'half_lost')),
+    is_fixed         BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS selections_ticket_id_idx ON selections (ticket_id);
+CREATE INDEX IF NOT EXISTS selections_eid_idx ON selections (eid);
+CREATE INDEX IF NOT EXISTS selections_status_idx ON selections (status);
+
+CREATE TABLE IF NOT EXISTS combinations (
+    combination_id        BIGSERIAL PRIMARY KEY,
+    ticket_id             INTEGER NOT NULL REFERENCES tickets (ticket_id) ON DELETE CASCADE,
+    selection_ids         INTEGER[] NOT NULL,
+    combination_odds      NUMERIC NOT NULL,
+    stake_per_combination NUMERIC NOT NULL,
+    potential_win         NUMERIC NOT NULL,
+    status                TEXT NOT NULL DEFAULT 'pending'
+                          CHECK (status IN ('pending', 'won', 'lost', 'void', 'cashed_out')),
+    final_payout          NUMERIC NOT NULL DEFAULT 0,
+    created_at            TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS combinations_ticket_id_idx ON combinations (ticket_id);
+CREATE INDEX IF NOT EXISTS combinations_status_idx ON combinations (status);
+
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS combination_storage TEXT NOT NULL DEFAULT 'rows'
+    CHECK (combination_storage IN ('rows', 'virtual'));
+ALTER TABLE tickets ADD COLUMN IF NOT EXISTS payout_cap NUMERIC CHECK (payout_cap > 0);
+ALTER TABLE combinations ADD COLUMN IF NOT EXISTS final_payout NUMERIC NOT NULL DEFAULT 0;
+
+-- Ograničenja se postavljaju pod imenima koja im Postgres daje u CREATE TABLE iznad, pa
+-- na novoj bazi samo zamenjuju sama sebe, a na preuzetoj menjaju ručno napravljena.
+ALTER TABLE tickets
+    ALTER COLUMN user_id SET NOT NULL,
+    ALTER COLUMN total_stake SET NOT NULL,
+    ALTER COLUMN total_odd SET NOT NULL,
+    ALTER COLUMN potential_payout SET NOT NULL,
+    ALTER COLUMN hits SET NOT NULL,
+    ALTER COLUMN misses SET NOT NULL,
+    ALTER COLUMN pending SET NOT NULL,
+    ALTER COLUMN status SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN max_payout SET NOT NULL,
+    ALTER COLUMN min_payout SET NOT NULL,
+    ALTER COLUMN final_payout SET NOT NULL,
+    ALTER COLUMN num_combinations SET NOT NULL,
+    ALTER COLUMN ticket_type SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS tickets_total_stake_check,
+    ADD CONSTRAINT tickets_total_stake_check CHECK (total_stake > 0) NOT VALID,
+    DROP CONSTRAINT IF EXISTS tickets_status_check,
+    ADD CONSTRAINT tickets_status_check
+        CHECK (status IN ('pending', 'won', 'lost', 'void', 'cashed_out')) NOT VALID,
+    DROP CONSTRAINT IF EXISTS tickets_combination_storage_check,
+    ADD CONSTRAINT tickets_combination_storage_check CHECK (combination_storage IN ('rows', 'virtual')) NOT VALID,
+    DROP CONSTRAINT IF EXISTS tickets_payout_cap_check,
+    ADD CONSTRAINT tickets_payout_cap_check CHECK (payout_cap > 0) NOT VALID;
+
+ALTER TABLE selections
+    ALTER COLUMN ticket_id SET NOT NULL,
+    ALTER COLUMN event_date SET NOT NULL,
+    ALTER COLUMN odd_value SET NOT NULL,
+    ALTER COLUMN status SET NOT NULL,
+    ALTER COLUMN is_fixed SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS selections_ticket_id_fkey,
+    ADD CONSTRAINT selections_ticket_id_fkey
+        FOREIGN KEY (ticket_id) REFERENCES tickets (ticket_id) ON DELETE CASCADE NOT VALID,
+    DROP CONSTRAINT IF EXISTS selections_odd_value_check,
+    ADD CONSTRAINT selections_odd_value_check CHECK (odd_value > 0) NOT VALID,
+    DROP CONSTRAINT IF EXISTS selections_status_check,
+    ADD CONSTRAINT selections_status_check
+        CHECK (status IN ('pending', 'won', 'lost', 'void', 'half_won', 'half_lost')) NOT VALID;
+
+ALTER TABLE combinations
+    ALTER COLUMN ticket_id SET NOT NULL,
+    ALTER COLUMN selection_ids SET NOT NULL,
+    ALTER COLUMN combination_odds SET NOT NULL,
+    ALTER COLUMN stake_per_combination SET NOT NULL,
+    ALTER COLUMN potential_win SET NOT NULL,
+    ALTER COLUMN status SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS combinations_ticket_id_fkey,
+    ADD CONSTRAINT combinations_ticket_id_fkey
+        FOREIGN KEY (ticket_id) REFERENCES tickets (ticket_id) ON DELETE CASCADE NOT VALID,
+    DROP CONSTRAINT IF EXISTS combinations_status_check,
+    ADD CONSTRAINT combinations_status_check
+        CHECK (status IN ('pending', 'won', 'lost', 'void', 'cashed_out')) NOT VALID;
+
+ALTER TABLE tickets VALIDATE CONSTRAINT tickets_total_stake_check;
+ALTER TABLE tickets VALIDATE CONSTRAINT tickets_status_check;
+ALTER TABLE tickets VALIDATE CONSTRAINT tickets_combination_storage_check;
+ALTER TABLE tickets VALIDATE CONSTRAINT tickets_payout_cap_check;
+ALTER TABLE selections VALIDATE CONSTRAINT selections_ticket_id_fkey;
+ALTER TABLE selections VALIDATE CONSTRAINT selections_odd_value_check;
+ALTER TABLE selections VALIDATE CONSTRAINT selections_status_check;
+ALTER TABLE combinations VALIDATE CONSTRAINT combinations_ticket_id_fkey;
+ALTER TABLE combinations VALIDATE CONSTRAINT combinations_status_check;
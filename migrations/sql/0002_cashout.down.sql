@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS cashout_ledger;
+DROP TABLE IF EXISTS combination_stake_changes;
+ALTER TABLE tickets DROP COLUMN IF EXISTS cashed_out_amount;
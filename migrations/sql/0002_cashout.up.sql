@@ -0,0 +1,24 @@
+ALTER TABLE tickets ADD COLUMN cashed_out_amount NUMERIC NOT NULL DEFAULT 0 CHECK (cashed_out_amount >= 0);
+
+CREATE TABLE combination_stake_changes (
+    change_id      BIGSERIAL PRIMARY KEY,
+    combination_id BIGINT NOT NULL REFERENCES combinations (combination_id) ON DELETE CASCADE,
+    ticket_id      INTEGER NOT NULL REFERENCES tickets (ticket_id) ON DELETE CASCADE,
+    old_stake      NUMERIC NOT NULL,
+    new_stake      NUMERIC NOT NULL CHECK (new_stake >= 0),
+    reason         TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX combination_stake_changes_ticket_id_idx ON combination_stake_changes (ticket_id);
+
+CREATE TABLE cashout_ledger (
+    entry_id   BIGSERIAL PRIMARY KEY,
+    ticket_id  INTEGER NOT NULL REFERENCES tickets (ticket_id) ON DELETE CASCADE,
+    quote_id   TEXT NOT NULL UNIQUE,
+    percent    NUMERIC NOT NULL CHECK (percent > 0 AND percent < 100),
+    amount     NUMERIC NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX cashout_ledger_ticket_id_idx ON cashout_ledger (ticket_id);
@@ -0,0 +1,11 @@
+CREATE TABLE ticket_liabilities (
+    ticket_id        INTEGER NOT NULL REFERENCES tickets (ticket_id) ON DELETE CASCADE,
+    selection_id     INTEGER NOT NULL REFERENCES selections (selection_id) ON DELETE CASCADE,
+    eid              TEXT NOT NULL,
+    market_type      TEXT NOT NULL,
+    selected_outcome TEXT NOT NULL,
+    liability        NUMERIC NOT NULL CHECK (liability >= 0),
+    PRIMARY KEY (ticket_id, selection_id)
+);
+
+CREATE INDEX ticket_liabilities_outcome_idx ON ticket_liabilities (eid, market_type, selected_outcome);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS wallet_transactions;
+DROP TABLE IF EXISTS wallet_accounts;
+ALTER TABLE tickets DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,38 @@
+ALTER TABLE tickets ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'EUR';
+
+CREATE TABLE wallet_accounts (
+    account_id BIGSERIAL PRIMARY KEY,
+    kind       TEXT NOT NULL CHECK (kind IN ('player', 'house', 'external')),
+    user_id    INTEGER,
+    currency   CHAR(3) NOT NULL,
+    balance    NUMERIC NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((kind = 'player') = (user_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX wallet_accounts_player_idx ON wallet_accounts (user_id, currency) WHERE kind = 'player';
+CREATE UNIQUE INDEX wallet_accounts_system_idx ON wallet_accounts (kind, currency) WHERE kind <> 'player';
+
+CREATE TABLE wallet_transactions (
+    transaction_id BIGSERIAL PRIMARY KEY,
+    kind           TEXT NOT NULL CHECK (kind IN ('deposit', 'stake', 'payout', 'refund', 'cashout')),
+    ticket_id      INTEGER REFERENCES tickets (ticket_id),
+    reference      TEXT UNIQUE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX wallet_transactions_ticket_id_idx ON wallet_transactions (ticket_id);
+
+CREATE TABLE ledger_entries (
+    entry_id       BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL REFERENCES wallet_transactions (transaction_id),
+    account_id     BIGINT NOT NULL REFERENCES wallet_accounts (account_id),
+    ticket_id      INTEGER REFERENCES tickets (ticket_id),
+    amount         NUMERIC NOT NULL CHECK (amount <> 0),
+    balance_after  NUMERIC,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX ledger_entries_account_idx ON ledger_entries (account_id, entry_id DESC);
+CREATE INDEX ledger_entries_ticket_id_idx ON ledger_entries (ticket_id);
+CREATE INDEX ledger_entries_transaction_id_idx ON ledger_entries (transaction_id);
@@ -0,0 +1,8 @@
+CREATE TABLE live_odds (
+    eid              TEXT NOT NULL,
+    market_type      TEXT NOT NULL,
+    selected_outcome TEXT NOT NULL,
+    odd              NUMERIC NOT NULL CHECK (odd > 1),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (eid, market_type, selected_outcome)
+);
@@ -0,0 +1,32 @@
+package models
+
+// LimitViolation je telo odgovora 422 kada tiket prekorači ograničenje uplate.
+type LimitViolation struct {
+	Error   string  `json:"error"`
+	Limit   string  `json:"limit"`
+	Value   float64 `json:"value"`
+	Allowed float64 `json:"allowed"`
+	Message string  `json:"message"`
+}
+
+type OutcomeLiability struct {
+	Eid             string  `json:"eid"`
+	MarketType      string  `json:"market_type"`
+	SelectedOutcome string  `json:"selected_outcome"`
+	Liability       float64 `json:"liability"`
+	Tickets         int     `json:"tickets"`
+}
+
+// EventLiability je najgori slučaj događaja: zbir najveće izloženosti po tržištu,
+// a gubitak je taj iznos umanjen za uloge tiketa koji sadrže događaj.
+type EventLiability struct {
+	Eid             string  `json:"eid"`
+	WorstCasePayout float64 `json:"worst_case_payout"`
+	Stake           float64 `json:"stake"`
+	WorstCaseLoss   float64 `json:"worst_case_loss"`
+}
+
+type LiabilityReport struct {
+	Outcomes []OutcomeLiability `json:"outcomes"`
+	Events   []EventLiability   `json:"events"`
+}
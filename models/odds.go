@@ -0,0 +1,17 @@
+package models
+
+// LiveOdd je trenutna kvota ishoda koju objavljuje servis kvota.
+type LiveOdd struct {
+	Eid             string  `json:"eid"`
+	MarketType      string  `json:"market_type"`
+	SelectedOutcome string  `json:"selected_outcome"`
+	Odd             float64 `json:"odd"`
+}
+
+type PublishOddsRequest struct {
+	Odds []LiveOdd `json:"odds"`
+}
+
+type PublishOddsResponse struct {
+	Updated int `json:"updated"`
+}
@@ -0,0 +1,31 @@
+package models
+
+type QuoteCombination struct {
+	SelectionIndexes []int   `json:"selection_indexes"`
+	Odds             float64 `json:"odds"`
+	PotentialWin     float64 `json:"potential_win"`
+}
+
+type TicketQuote struct {
+	NumCombinations     int                `json:"num_combinations"`
+	StakePerCombination float64            `json:"stake_per_combination"`
+	MinPayout           float64            `json:"min_payout"`
+	MaxPayout           float64            `json:"max_payout"`
+	Combinations        []QuoteCombination `json:"combinations,omitempty"`
+}
+
+type WinnersPayout struct {
+	Winners             int     `json:"winners"`
+	WinningCombinations int     `json:"winning_combinations"`
+	MinPayout           float64 `json:"min_payout"`
+	MaxPayout           float64 `json:"max_payout"`
+}
+
+type PayoutTable struct {
+	NumCombinations     int             `json:"num_combinations"`
+	StakePerCombination float64         `json:"stake_per_combination"`
+	FreeSelections      int             `json:"free_selections"`
+	FixedSelections     int             `json:"fixed_selections"`
+	BankersMustWin      bool            `json:"bankers_must_win"`
+	Rows                []WinnersPayout `json:"rows"`
+}
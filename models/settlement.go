@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+type SelectionResult struct {
+	SelectionID int    `json:"selection_id"`
+	Status      string `json:"status"`
+}
+
+type SettlementRequest struct {
+	Results []SelectionResult `json:"results"`
+}
+
+type SettlementResponse struct {
+	TicketIDs []int `json:"ticket_ids"`
+}
+
+type EventResult struct {
+	Eid        string            `json:"eid"`
+	MarketType string            `json:"market_type"`
+	Result     string            `json:"result"`
+	Periods    map[string]string `json:"periods,omitempty"`
+}
+
+// EventSettlementResponse vraća broj obračunatih selekcija, pogođene tikete i selekcije
+// koje nisu mogle biti ocenjene i ostale su na čekanju.
+type EventSettlementResponse struct {
+	SettledSelections int                 `json:"settled_selections"`
+	TicketIDs         []int               `json:"ticket_ids"`
+	Ungraded          []UngradedSelection `json:"ungraded"`
+}
+
+type UngradedSelection struct {
+	SelectionID int    `json:"selection_id"`
+	Error       string `json:"error"`
+}
+
+type CashOutQuote struct {
+	QuoteID     string    `json:"quote_id"`
+	TicketID    int       `json:"ticket_id"`
+	FairValue   float64   `json:"fair_value"`
+	Amount      float64   `json:"amount"`
+	PartialBase float64   `json:"partial_base"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type CashOutAcceptRequest struct {
+	QuoteID string `json:"quote_id"`
+}
+
+type CashOutResponse struct {
+	TicketID    int     `json:"ticket_id"`
+	Status      string  `json:"status"`
+	FinalPayout float64 `json:"final_payout"`
+}
+
+type PartialCashOutRequest struct {
+	QuoteID string  `json:"quote_id"`
+	Percent float64 `json:"percent"`
+}
+
+type PartialCashOutResponse struct {
+	TicketID       int     `json:"ticket_id"`
+	Status         string  `json:"status"`
+	Percent        float64 `json:"percent"`
+	Amount         float64 `json:"amount"`
+	RemainingStake float64 `json:"remaining_stake"`
+}
@@ -0,0 +1,33 @@
+package models
+
+const (
+	StatusPending  = "pending"
+	StatusWon      = "won"
+	StatusLost     = "lost"
+	StatusVoid     = "void"
+	StatusHalfWon  = "half_won"
+	StatusHalfLost = "half_lost"
+
+	StatusCashedOut = "cashed_out"
+)
+
+// Način čuvanja kombinacija sistemskog tiketa: "rows" upisuje svaku kombinaciju u tabelu
+// combinations, a "virtual" čuva samo sistem i selekcije i kombinacije računa po potrebi.
+const (
+	StorageRows    = "rows"
+	StorageVirtual = "virtual"
+)
+
+// IsTicketStatus vraća true za sve statuse koje tiket može imati.
+func IsTicketStatus(status string) bool {
+	return status == StatusPending || status == StatusCashedOut || IsSettlementStatus(status)
+}
+
+// IsSettlementStatus vraća true za statuse koji se mogu dodeliti selekciji prilikom obračuna.
+func IsSettlementStatus(status string) bool {
+	switch status {
+	case StatusWon, StatusLost, StatusVoid, StatusHalfWon, StatusHalfLost:
+		return true
+	}
+	return false
+}
@@ -20,25 +20,35 @@ type Ticket struct {
 	TicketType        string
 	Selections        []Selection
 	Logo              string
+	// CombinationStorage je "rows" (podrazumevano) ili "virtual"
+	CombinationStorage string
+	// Currency je valuta uloga i isplate; prazna znači podrazumevanu valutu servisa
+	Currency string
+	// PayoutCap postavljaju limiti uplate, klijent ga ne može zadati
+	PayoutCap float64 `json:"-"`
 }
 
 type DBTicket struct {
-	TicketID          int
-	UserID            int
-	TotalStake        float64
-	TotalOdd          float64
-	PotentialPayout   float64
-	Hits              int
-	Misses            int
-	Pending           int
-	Status            string
-	CreatedAt         time.Time
-	MaxPayout         float64
-	MinPayout         float64
-	FinalPayout       float64
-	NumCombinations   int
-	SystemCombination *string
-	TicketType        string
+	TicketID           int       `json:"ticket_id"`
+	UserID             int       `json:"user_id"`
+	TotalStake         float64   `json:"total_stake"`
+	TotalOdd           float64   `json:"total_odd"`
+	PotentialPayout    float64   `json:"potential_payout"`
+	Hits               int       `json:"hits"`
+	Misses             int       `json:"misses"`
+	Pending            int       `json:"pending"`
+	Status             string    `json:"status"`
+	CreatedAt          time.Time `json:"created_at"`
+	MaxPayout          float64   `json:"max_payout"`
+	MinPayout          float64   `json:"min_payout"`
+	FinalPayout        float64   `json:"final_payout"`
+	NumCombinations    int       `json:"num_combinations"`
+	SystemCombination  *string   `json:"system_combination"`
+	TicketType         string    `json:"ticket_type"`
+	CashedOutAmount    float64   `json:"cashed_out_amount"`
+	CombinationStorage string    `json:"combination_storage"`
+	PayoutCap          *float64  `json:"payout_cap"`
+	Currency           string    `json:"currency"`
 }
 
 type Selection struct {
@@ -58,31 +68,54 @@ type Selection struct {
 }
 
 type DBSelection struct {
-	ID              int
-	TicketID        int
-	SportType       string
-	League          string
-	HomeTeam        string
-	AwayTeam        string
-	EventDate       time.Time
-	MarketType      string
-	SelectedOutcome string
-	OddValue        float64
-	Stake           float64
-	Eid             string
-	SelectionType   string
-	Status          string
-	IsFixed         bool
+	ID              int       `json:"selection_id"`
+	TicketID        int       `json:"ticket_id"`
+	SportType       string    `json:"sport_type"`
+	League          string    `json:"league"`
+	HomeTeam        string    `json:"home_team"`
+	AwayTeam        string    `json:"away_team"`
+	EventDate       time.Time `json:"event_date"`
+	MarketType      string    `json:"market_type"`
+	SelectedOutcome string    `json:"selected_outcome"`
+	OddValue        float64   `json:"odd_value"`
+	Stake           float64   `json:"stake"`
+	Eid             string    `json:"eid"`
+	SelectionType   string    `json:"selection_type"`
+	Status          string    `json:"status"`
+	IsFixed         bool      `json:"is_fixed"`
 }
 
 type DBCombination struct {
-	CombinationID       int
-	TicketID            int
-	SelectionIDs        []int
-	CombinationOdds     float64
-	StakePerCombination float64
-	PotentialWin        float64
-	Status              string
-	FinalPayout         float64
-	CreatedAt           time.Time
+	CombinationID       int       `json:"combination_id"`
+	TicketID            int       `json:"ticket_id"`
+	SelectionIDs        []int     `json:"selection_ids"`
+	CombinationOdds     float64   `json:"combination_odds"`
+	StakePerCombination float64   `json:"stake_per_combination"`
+	PotentialWin        float64   `json:"potential_win"`
+	Status              string    `json:"status"`
+	FinalPayout         float64   `json:"final_payout"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type TicketDetails struct {
+	Ticket       DBTicket        `json:"ticket"`
+	Selections   []DBSelection   `json:"selections"`
+	Combinations []DBCombination `json:"combinations"`
+}
+
+// CombinationPage je stranica kombinacija tiketa. Za virtuelne tikete CombinationID je
+// rang kombinacije (0 je prva), a NextFrom je rang od kog se nastavlja.
+type CombinationPage struct {
+	TicketID     int             `json:"ticket_id"`
+	Total        int             `json:"total"`
+	From         int             `json:"from"`
+	NextFrom     *int            `json:"next_from"`
+	Combinations []DBCombination `json:"combinations"`
+}
+
+// TicketPage je stranica liste tiketa, od najnovijeg ka starijim; NextCursor se šalje
+// kao ?cursor= za sledeću stranicu i nil je na poslednjoj.
+type TicketPage struct {
+	Tickets    []DBTicket `json:"tickets"`
+	NextCursor *string    `json:"next_cursor"`
 }
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// DefaultCurrency je valuta tiketa, uplata i izvoda kada je klijent ne navede, osim ako
+// podešavanja ne zadaju drugu.
+const DefaultCurrency = "EUR"
+
+// Vrste transakcija novčanika. Ulog ide sa računa igrača na račun kuće, isplata,
+// povraćaj i isplata pre kraja obrnuto, a uplata dolazi sa spoljnog računa.
+const (
+	WalletDeposit = "deposit"
+	WalletStake   = "stake"
+	WalletPayout  = "payout"
+	WalletRefund  = "refund"
+	WalletCashOut = "cashout"
+)
+
+// Vrste računa novčanika.
+const (
+	AccountPlayer   = "player"
+	AccountHouse    = "house"
+	AccountExternal = "external"
+)
+
+type WalletBalance struct {
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+}
+
+type WalletBalances struct {
+	UserID   int             `json:"user_id"`
+	Balances []WalletBalance `json:"balances"`
+}
+
+// LedgerEntry je stavka izvoda igrača; BalanceAfter je stanje računa posle stavke.
+type LedgerEntry struct {
+	EntryID       int64     `json:"entry_id"`
+	TransactionID int64     `json:"transaction_id"`
+	Kind          string    `json:"kind"`
+	TicketID      *int      `json:"ticket_id"`
+	Amount        float64   `json:"amount"`
+	BalanceAfter  float64   `json:"balance_after"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WalletStatement je stranica izvoda jednog računa, od najnovije stavke ka starijim;
+// NextCursor se šalje kao ?cursor= za sledeću stranicu i nil je na poslednjoj.
+type WalletStatement struct {
+	UserID     int           `json:"user_id"`
+	Currency   string        `json:"currency"`
+	Balance    float64       `json:"balance"`
+	Entries    []LedgerEntry `json:"entries"`
+	NextCursor *string       `json:"next_cursor"`
+}
+
+// DepositRequest je uplata na račun igrača; Reference (ID uplate kod provajdera) je
+// obavezna i sprečava da se ista uplata knjiži dvaput.
+type DepositRequest struct {
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+	Reference string  `json:"reference"`
+}
+
+type DepositResponse struct {
+	TransactionID int64   `json:"transaction_id"`
+	Currency      string  `json:"currency"`
+	Balance       float64 `json:"balance"`
+}
+
+// InsufficientFunds je telo odgovora 422 kada stanje računa ne pokriva ulog tiketa.
+type InsufficientFunds struct {
+	Error    string  `json:"error"`
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+	Required float64 `json:"required"`
+	Message  string  `json:"message"`
+}
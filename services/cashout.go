@@ -0,0 +1,435 @@
+package services
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/db"
+	"goticketsistem/models"
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCashOutUnavailable = errors.New("cash-out not available")
+	ErrQuoteNotFound      = errors.New("cash-out quote not found")
+	ErrQuoteExpired       = errors.New("cash-out quote expired")
+	ErrQuoteStale         = errors.New("cash-out quote no longer matches ticket state")
+	ErrInvalidLiveOdds    = errors.New("invalid live odds")
+
+	ErrInvalidCashOutPercent = errors.New("invalid cash-out percent")
+)
+
+const (
+	DefaultCashOutMargin     = 0.05
+	DefaultCashOutQuoteTTL   = 30 * time.Second
+	DefaultCashOutMaxOddsAge = time.Minute
+)
+
+type cashOutQuote struct {
+	quote models.CashOutQuote
+	// Stanje tiketa u trenutku ponude; ponuda važi samo dok se ono ne promeni
+	snapshot cashOutSnapshot
+}
+
+type cashOutSnapshot struct {
+	legStatuses  map[int64]string
+	pendingStake float64
+}
+
+type CashOutService struct {
+	db         *db.DBManager
+	margin     float64
+	quoteTTL   time.Duration
+	maxOddsAge time.Duration
+
+	mux    sync.Mutex
+	quotes map[string]cashOutQuote
+}
+
+func NewCashOutService(db *db.DBManager, margin float64, quoteTTL time.Duration) *CashOutService {
+	return &CashOutService{db: db, margin: margin, quoteTTL: quoteTTL, maxOddsAge: DefaultCashOutMaxOddsAge,
+		quotes: make(map[string]cashOutQuote)}
+}
+
+// SetMaxOddsAge postavlja najveću starost trenutne kvote koja se koristi za ponudu.
+func (cs *CashOutService) SetMaxOddsAge(maxAge time.Duration) {
+	cs.maxOddsAge = maxAge
+}
+
+// Quote računa ponudu za isplatu tiketa pre kraja. Trenutne (live) kvote selekcija na
+// čekanju uzimaju se iz live_odds; bez sveže kvote za svaku od njih ponuda nije moguća.
+func (cs *CashOutService) Quote(ticketID int) (*models.CashOutQuote, error) {
+	tx, err := cs.db.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	legs, combos, virtual, err := loadCashOutState(tx, ticketID, false)
+	if err != nil {
+		return nil, err
+	}
+	liveOdds, err := loadLiveOdds(tx, ticketID, cs.maxOddsAge)
+	if err != nil {
+		return nil, err
+	}
+	fairValue, pendingValue, err := cashOutValue(legs, combos, virtual, liveOdds)
+	if err != nil {
+		return nil, err
+	}
+
+	quoteID, err := newQuoteID()
+	if err != nil {
+		return nil, err
+	}
+	quote := models.CashOutQuote{
+		QuoteID:   quoteID,
+		TicketID:  ticketID,
+		FairValue: roundMoney(fairValue),
+		Amount:    roundMoney(fairValue * (1 - cs.margin)),
+		// Delimična isplata se računa samo od kombinacija koje su još na čekanju
+		PartialBase: roundMoney(pendingValue * (1 - cs.margin)),
+		ExpiresAt:   time.Now().Add(cs.quoteTTL),
+	}
+
+	cs.mux.Lock()
+	defer cs.mux.Unlock()
+	cs.purgeExpired(time.Now())
+	cs.quotes[quoteID] = cashOutQuote{quote: quote, snapshot: takeSnapshot(legs, combos)}
+
+	log.Printf("Cash-out quote %s for ticket %d: fair_value=%f, amount=%f", quoteID, ticketID, quote.FairValue, quote.Amount)
+	return &quote, nil
+}
+
+// Accept izvršava ponudu: tiket dobija status "cashed_out" i ponuđeni iznos kao konačnu
+// isplatu, koja se knjiži na račun igrača.
+func (cs *CashOutService) Accept(ticketID int, quoteID string) (*models.CashOutResponse, error) {
+	q, err := cs.takeQuote(ticketID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := cs.db.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	legs, combos, _, err := loadCashOutState(tx, ticketID, true)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if !q.snapshot.matches(legs, combos) {
+		tx.Rollback()
+		return nil, ErrQuoteStale
+	}
+
+	if err := closeCombinations(tx, combos, models.StatusCashedOut); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE tickets SET status = $1, final_payout = $2 WHERE ticket_id = $3`,
+		models.StatusCashedOut, q.quote.Amount, ticketID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update ticket %d: %v", ticketID, err)
+	}
+	if err := creditCashOut(tx, ticketID, quoteID, q.quote.Amount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := refreshTicketLiability(tx, ticketID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("Ticket %d cashed out for %f (quote %s)", ticketID, q.quote.Amount, quoteID)
+	return &models.CashOutResponse{TicketID: ticketID, Status: models.StatusCashedOut, FinalPayout: q.quote.Amount}, nil
+}
+
+// AcceptPartial isplaćuje dati procenat vrednosti kombinacija na čekanju. Ulog i mogući
+// dobitak svake kombinacije na čekanju smanjuju se u istom odnosu, svaka promena uloga se
+// beleži, iznos se knjiži na račun igrača, a tiket ostaje otvoren za preostali deo.
+func (cs *CashOutService) AcceptPartial(ticketID int, quoteID string, percent float64) (*models.PartialCashOutResponse, error) {
+	if percent <= 0 || percent >= 100 {
+		return nil, fmt.Errorf("%w: percent must be between 0 and 100", ErrInvalidCashOutPercent)
+	}
+	q, err := cs.takeQuote(ticketID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := cs.db.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	legs, combos, virtual, err := loadCashOutState(tx, ticketID, true)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if virtual != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: partial cash-out needs stored combinations", ErrCashOutUnavailable)
+	}
+	if !q.snapshot.matches(legs, combos) {
+		tx.Rollback()
+		return nil, ErrQuoteStale
+	}
+
+	ratio := percent / 100
+	amount := roundMoney(q.quote.PartialBase * ratio)
+	if amount <= 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: nothing left to cash out", ErrCashOutUnavailable)
+	}
+
+	var remainingStake, potentialReduction float64
+	for _, c := range combos {
+		if c.status != models.StatusPending {
+			continue
+		}
+		newStake := c.stake * (1 - ratio)
+		if _, err := tx.Exec(`UPDATE combinations SET stake_per_combination = $1, potential_win = $2 WHERE combination_id = $3`,
+			newStake, c.potentialWin*(1-ratio), c.id); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to update combination %d: %v", c.id, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO combination_stake_changes (combination_id, ticket_id, old_stake, new_stake, reason, created_at)
+                 VALUES ($1, $2, $3, $4, $5, $6)`, c.id, ticketID, c.stake, newStake, "partial_cashout:"+quoteID, time.Now()); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to record stake change for combination %d: %v", c.id, err)
+		}
+		remainingStake += newStake
+		potentialReduction += c.potentialWin * ratio
+	}
+
+	if _, err := tx.Exec(`INSERT INTO cashout_ledger (ticket_id, quote_id, percent, amount, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		ticketID, quoteID, percent, amount, time.Now()); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to record cash-out for ticket %d: %v", ticketID, err)
+	}
+	if _, err := tx.Exec(`UPDATE tickets SET cashed_out_amount = cashed_out_amount + $1,
+             max_payout = GREATEST(max_payout - $2, 0), potential_payout = GREATEST(potential_payout - $2, 0) WHERE ticket_id = $3`,
+		amount, potentialReduction, ticketID); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update ticket %d: %v", ticketID, err)
+	}
+	if err := creditCashOut(tx, ticketID, quoteID, amount); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := refreshTicketLiability(tx, ticketID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("Ticket %d partially cashed out: %.2f%% for %f, remaining stake %f", ticketID, percent, amount, remainingStake)
+	return &models.PartialCashOutResponse{
+		TicketID:       ticketID,
+		Status:         models.StatusPending,
+		Percent:        percent,
+		Amount:         amount,
+		RemainingStake: remainingStake,
+	}, nil
+}
+
+// takeQuote uklanja ponudu iz skladišta; svaka ponuda može biti iskorišćena samo jednom.
+func (cs *CashOutService) takeQuote(ticketID int, quoteID string) (cashOutQuote, error) {
+	cs.mux.Lock()
+	q, ok := cs.quotes[quoteID]
+	if ok {
+		delete(cs.quotes, quoteID)
+	}
+	cs.mux.Unlock()
+
+	if !ok || q.quote.TicketID != ticketID {
+		return cashOutQuote{}, ErrQuoteNotFound
+	}
+	if time.Now().After(q.quote.ExpiresAt) {
+		return cashOutQuote{}, ErrQuoteExpired
+	}
+	return q, nil
+}
+
+func (cs *CashOutService) purgeExpired(now time.Time) {
+	for id, q := range cs.quotes {
+		if now.After(q.quote.ExpiresAt) {
+			delete(cs.quotes, id)
+		}
+	}
+}
+
+// virtualSystem je stanje tiketa bez sačuvanih kombinacija, potrebno za analitički proračun.
+type virtualSystem struct {
+	selectionIDs        []int
+	legs                []calc.Leg
+	spec                calc.SystemSpec
+	stakePerCombination float64
+}
+
+// loadCashOutState učitava selekcije i kombinacije tiketa koji je još na čekanju.
+// Za virtuelni tiket umesto kombinacija vraća sistem iz kog se one računaju.
+func loadCashOutState(tx *sql.Tx, ticketID int, lock bool) (map[int64]calc.Leg, []combinationRow, *virtualSystem, error) {
+	query := `SELECT status, COALESCE(combination_storage, 'rows'), system_combination, total_stake, num_combinations
+             FROM tickets WHERE ticket_id = $1`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	var status, storage string
+	var systemCombination sql.NullString
+	var totalStake float64
+	var numCombinations int
+	err := tx.QueryRow(query, ticketID).Scan(&status, &storage, &systemCombination, &totalStake, &numCombinations)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load ticket %d: %v", ticketID, err)
+	}
+	if status != models.StatusPending {
+		return nil, nil, nil, fmt.Errorf("%w: ticket %d is %s", ErrCashOutUnavailable, ticketID, status)
+	}
+
+	legs, err := loadSettlementLegs(tx, ticketID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if storage == models.StorageVirtual {
+		spec, err := calc.ParseSystem(systemCombination.String)
+		if err != nil || numCombinations <= 0 {
+			return nil, nil, nil, fmt.Errorf("ticket %d has an invalid system: %v", ticketID, err)
+		}
+		vs := &virtualSystem{spec: spec, stakePerCombination: totalStake / float64(numCombinations)}
+		if vs.selectionIDs, vs.legs, err = loadTicketLegs(tx, ticketID); err != nil {
+			return nil, nil, nil, err
+		}
+		return legs, nil, vs, nil
+	}
+
+	combos, err := loadCombinationRows(tx, ticketID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return legs, combos, nil, nil
+}
+
+// cashOutValue računa fer vrednost tiketa: već obračunate kombinacije ulaze svojom isplatom,
+// a kombinacije na čekanju mogućim dobitkom pomnoženim verovatnoćom da preostale noge prođu,
+// procenjenom odnosom originalne i trenutne kvote (vidi liveFactor). Drugi rezultat je deo
+// vrednosti koji otpada na kombinacije na čekanju.
+func cashOutValue(legs map[int64]calc.Leg, combos []combinationRow, virtual *virtualSystem, liveOdds map[int]float64) (float64, float64, error) {
+	for id, leg := range legs {
+		if leg.Status != models.StatusPending {
+			continue
+		}
+		live, ok := liveOdds[int(id)]
+		if !ok || live <= 1 {
+			return 0, 0, fmt.Errorf("%w: missing or invalid value for selection %d", ErrInvalidLiveOdds, id)
+		}
+	}
+
+	if virtual != nil {
+		value, settledValue := calc.SystemValue(virtual.legs, virtual.spec, virtual.stakePerCombination, func(i int) float64 {
+			return liveFactor(virtual.legs[i].Odd, liveOdds[virtual.selectionIDs[i]])
+		})
+		return value, value - settledValue, nil
+	}
+
+	var value, pendingValue float64
+	for _, c := range combos {
+		v := combinationCashOutValue(c, legs, liveOdds)
+		value += v
+		if c.status == models.StatusPending {
+			pendingValue += v
+		}
+	}
+	return value, pendingValue, nil
+}
+
+func combinationCashOutValue(c combinationRow, legs map[int64]calc.Leg, liveOdds map[int]float64) float64 {
+	if c.status != models.StatusPending {
+		return c.finalPayout
+	}
+	value := c.stake
+	for _, id := range c.selectionIDs {
+		leg := legs[id]
+		if leg.Status == models.StatusPending {
+			value *= liveFactor(leg.Odd, liveOdds[int(id)])
+			continue
+		}
+		value *= calc.LegFactor(leg)
+	}
+	return value
+}
+
+// liveFactor je koeficijent noge na čekanju: odnos originalne i trenutne kvote. Noga čija
+// je kvota pala vredi više od svog dela uloga, što je i svrha isplate pre kraja; trenutne
+// kvote dolaze iz live_odds, a ne od igrača.
+func liveFactor(odd, live float64) float64 {
+	return odd / live
+}
+
+// closeCombinations zatvara sve kombinacije na čekanju datim statusom.
+func closeCombinations(tx *sql.Tx, combos []combinationRow, status string) error {
+	for _, c := range combos {
+		if c.status != models.StatusPending {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE combinations SET status = $1 WHERE combination_id = $2`, status, c.id); err != nil {
+			return fmt.Errorf("failed to update combination %d: %v", c.id, err)
+		}
+	}
+	return nil
+}
+
+func takeSnapshot(legs map[int64]calc.Leg, combos []combinationRow) cashOutSnapshot {
+	snap := cashOutSnapshot{legStatuses: make(map[int64]string, len(legs))}
+	for id, leg := range legs {
+		snap.legStatuses[id] = leg.Status
+	}
+	for _, c := range combos {
+		if c.status == models.StatusPending {
+			snap.pendingStake += c.stake
+		}
+	}
+	return snap
+}
+
+func (snap cashOutSnapshot) matches(legs map[int64]calc.Leg, combos []combinationRow) bool {
+	current := takeSnapshot(legs, combos)
+	if len(current.legStatuses) != len(snap.legStatuses) || math.Abs(current.pendingStake-snap.pendingStake) > 1e-9 {
+		return false
+	}
+	for id, status := range current.legStatuses {
+		if snap.legStatuses[id] != status {
+			return false
+		}
+	}
+	return true
+}
+
+func newQuoteID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate quote id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func roundMoney(v float64) float64 {
+	return math.Floor(v*100) / 100
+}
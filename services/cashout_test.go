@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"goticketsistem/calc"
+	"goticketsistem/models"
+)
+
+func TestCashOutValue(t *testing.T) {
+	pending := func(odd float64) calc.Leg { return calc.Leg{Odd: odd, Status: models.StatusPending} }
+	won := func(odd float64) calc.Leg { return calc.Leg{Odd: odd, Status: models.StatusWon} }
+	single := func(stake float64, ids ...int64) []combinationRow {
+		return []combinationRow{{id: 1, selectionIDs: ids, stake: stake, status: models.StatusPending}}
+	}
+
+	tests := []struct {
+		name     string
+		legs     map[int64]calc.Leg
+		combos   []combinationRow
+		liveOdds map[int]float64
+		want     float64
+	}{
+		// Kvota je pala sa 3.0 na 1.05: tiket vredi 10 * 3 / 1.05, a ne samo ulog
+		{"shortened leg", map[int64]calc.Leg{1: pending(3)}, single(10, 1), map[int]float64{1: 1.05}, 10 * 3 / 1.05},
+		{"unchanged odds", map[int64]calc.Leg{1: pending(3)}, single(10, 1), map[int]float64{1: 3}, 10},
+		{"drifted leg", map[int64]calc.Leg{1: pending(2)}, single(10, 1), map[int]float64{1: 4}, 5},
+		{"won and shortened", map[int64]calc.Leg{1: won(2), 2: pending(3)}, single(10, 1, 2), map[int]float64{2: 1.5}, 10 * 2 * 2},
+		{"settled combination keeps its payout", map[int64]calc.Leg{1: won(2)},
+			[]combinationRow{{id: 1, selectionIDs: []int64{1}, stake: 10, status: models.StatusWon, finalPayout: 20}}, nil, 20},
+	}
+	for _, tt := range tests {
+		value, _, err := cashOutValue(tt.legs, tt.combos, nil, tt.liveOdds)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if math.Abs(value-tt.want) > 1e-9 {
+			t.Errorf("%s: value %v, want %v", tt.name, value, tt.want)
+		}
+	}
+
+	if _, _, err := cashOutValue(map[int64]calc.Leg{1: pending(3)}, single(10, 1), nil, nil); !errors.Is(err, ErrInvalidLiveOdds) {
+		t.Errorf("missing live odds: error = %v, want ErrInvalidLiveOdds", err)
+	}
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const DefaultCombinationBatchSize = 1000
+
+// CombinationWriteOptions određuje kako se kombinacije upisuju: BatchSize je broj
+// kombinacija po paketu, a Copy bira PostgreSQL COPY umesto jednog INSERT-a po redu.
+type CombinationWriteOptions struct {
+	BatchSize int
+	Copy      bool
+}
+
+var DefaultCombinationWriteOptions = CombinationWriteOptions{BatchSize: DefaultCombinationBatchSize, Copy: true}
+
+var combinationColumns = []string{"ticket_id", "selection_ids", "combination_odds", "stake_per_combination",
+	"potential_win", "status", "created_at"}
+
+// combinationWriter upisuje kombinacije tiketa u paketima od BatchSize redova, tako da
+// se kombinacije mogu generisati lenjo i nikad ne drže sve u memoriji. Svi paketi idu
+// kroz istu transakciju, pa se greška u bilo kom paketu poništava zajedno sa tiketom.
+type combinationWriter struct {
+	tx           *sql.Tx
+	ticketID     int
+	selectionIDs []int
+	opts         CombinationWriteOptions
+	createdAt    time.Time
+
+	stmt    *sql.Stmt
+	batch   []calc.Combination
+	written int
+}
+
+func newCombinationWriter(tx *sql.Tx, ticketID int, selectionIDs []int, opts CombinationWriteOptions) *combinationWriter {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultCombinationBatchSize
+	}
+	return &combinationWriter{
+		tx:           tx,
+		ticketID:     ticketID,
+		selectionIDs: selectionIDs,
+		opts:         opts,
+		createdAt:    time.Now(),
+		batch:        make([]calc.Combination, 0, opts.BatchSize),
+	}
+}
+
+func (w *combinationWriter) Write(c calc.Combination) error {
+	w.batch = append(w.batch, c)
+	if len(w.batch) >= w.opts.BatchSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush upisuje kombinacije prikupljene u tekućem paketu.
+func (w *combinationWriter) Flush() error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	if w.opts.Copy {
+		return w.flushCopy()
+	}
+	if w.stmt == nil {
+		stmt, err := w.tx.Prepare(`INSERT INTO combinations (ticket_id, selection_ids, combination_odds, stake_per_combination, potential_win, status, created_at)
+             VALUES ($1, $2, $3, $4, $5, $6, $7)`)
+		if err != nil {
+			return fmt.Errorf("failed to prepare combination insert: %v", err)
+		}
+		w.stmt = stmt
+	}
+	for _, c := range w.batch {
+		if _, err := w.stmt.Exec(w.ticketID, pq.Array(w.selectionIDsFor(c)), c.Odds, c.Stake, c.PotentialWin,
+			models.StatusPending, w.createdAt); err != nil {
+			return fmt.Errorf("failed to insert combination: %v", err)
+		}
+	}
+	w.written += len(w.batch)
+	w.batch = w.batch[:0]
+	return nil
+}
+
+// flushCopy upisuje paket jednom COPY naredbom.
+func (w *combinationWriter) flushCopy() error {
+	stmt, err := w.tx.Prepare(pq.CopyIn("combinations", combinationColumns...))
+	if err != nil {
+		return fmt.Errorf("failed to start combination copy: %v", err)
+	}
+	for _, c := range w.batch {
+		if _, err := stmt.Exec(w.ticketID, pq.Array(w.selectionIDsFor(c)), c.Odds, c.Stake, c.PotentialWin,
+			models.StatusPending, w.createdAt); err != nil {
+			stmt.Close()
+			return fmt.Errorf("failed to copy combination: %v", err)
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return fmt.Errorf("failed to flush combination copy: %v", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("failed to finish combination copy: %v", err)
+	}
+	w.written += len(w.batch)
+	w.batch = w.batch[:0]
+	return nil
+}
+
+// Close upisuje ostatak paketa i oslobađa pripremljenu naredbu.
+func (w *combinationWriter) Close() error {
+	err := w.Flush()
+	if w.stmt != nil {
+		if cerr := w.stmt.Close(); err == nil && cerr != nil {
+			err = cerr
+		}
+		w.stmt = nil
+	}
+	return err
+}
+
+// selectionIDsFor prevodi pozicije nogu kombinacije u ID-jeve selekcija.
+func (w *combinationWriter) selectionIDsFor(c calc.Combination) []int64 {
+	ids := make([]int64, len(c.Legs))
+	for i, leg := range c.Legs {
+		ids[i] = int64(w.selectionIDs[leg])
+	}
+	return ids
+}
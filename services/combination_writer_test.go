@@ -0,0 +1,119 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"goticketsistem/calc"
+	"goticketsistem/migrations"
+	"goticketsistem/models"
+
+	_ "github.com/lib/pq"
+)
+
+// EnvTestDSN je promenljiva okruženja sa DSN-om test baze; bez nje se testovi i
+// benchmark-ovi koji pišu u bazu preskaču. Svaki upis se poništava na kraju iteracije.
+const EnvTestDSN = "TICKETS_TEST_DSN"
+
+func openTestDB(tb testing.TB) *sql.DB {
+	dsn := os.Getenv(EnvTestDSN)
+	if dsn == "" {
+		tb.Skipf("%s is not set", EnvTestDSN)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatalf("failed to open test database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		tb.Fatalf("failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// benchmarkTicket je sistem 1-20/20 (oko milion kombinacija), dovoljno za svaku veličinu benchmark-a.
+func benchmarkTicket() *models.Ticket {
+	ticket := &models.Ticket{UserID: 1, TotalStake: 100, TicketType: "system", SystemCombination: "1-20/20", Currency: models.DefaultCurrency}
+	for i := 0; i < 20; i++ {
+		ticket.Selections = append(ticket.Selections, models.Selection{
+			SportType: "football", League: "bench", HomeTeam: "home", AwayTeam: "away",
+			EventDate: time.Now().Add(24 * time.Hour), MarketType: "1X2", SelectedOutcome: "1",
+			OddValue: 1.5 + float64(i)/10, Stake: 1, Eid: fmt.Sprintf("bench-%d", i),
+		})
+	}
+	return ticket
+}
+
+// BenchmarkCombinationWriter poredi upis kombinacija INSERT-om po redu i COPY-jem za
+// 1k, 10k i 100k kombinacija. Pokreće se sa TICKETS_TEST_DSN, npr.
+// TICKETS_TEST_DSN="dbname=tickets_test sslmode=disable" go test -run ^$ -bench CombinationWriter ./services
+func BenchmarkCombinationWriter(b *testing.B) {
+	db := openTestDB(b)
+	ticket := benchmarkTicket()
+	spec, err := calc.ParseSystem(ticket.SystemCombination)
+	if err != nil {
+		b.Fatal(err)
+	}
+	plan, err := calc.NewSystemPlan(ticketLegs(ticket), spec, ticket.TotalStake)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	modes := []struct {
+		name string
+		copy bool
+	}{{"insert", false}, {"copy", true}}
+	for _, size := range []int{1000, 10000, 100000} {
+		combos := make([]calc.Combination, 0, size)
+		for c := range plan.Combinations() {
+			if len(combos) == size {
+				break
+			}
+			combos = append(combos, c)
+		}
+
+		for _, mode := range modes {
+			b.Run(fmt.Sprintf("%s/%d", mode.name, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					tx, err := db.Begin()
+					if err != nil {
+						b.Fatal(err)
+					}
+					ticketID, err := insertTicket(tx, ticket)
+					if err != nil {
+						tx.Rollback()
+						b.Fatal(err)
+					}
+					selectionIDs, _, err := loadTicketLegs(tx, ticketID)
+					if err != nil {
+						tx.Rollback()
+						b.Fatal(err)
+					}
+					writer := newCombinationWriter(tx, ticketID, selectionIDs,
+						CombinationWriteOptions{BatchSize: DefaultCombinationBatchSize, Copy: mode.copy})
+					b.StartTimer()
+
+					for _, c := range combos {
+						if err := writer.Write(c); err != nil {
+							tx.Rollback()
+							b.Fatal(err)
+						}
+					}
+					if err := writer.Close(); err != nil {
+						tx.Rollback()
+						b.Fatal(err)
+					}
+
+					b.StopTimer()
+					tx.Rollback()
+					b.StartTimer()
+				}
+				b.ReportMetric(float64(size)*float64(b.N)/b.Elapsed().Seconds(), "combinations/s")
+			})
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/db"
+	"goticketsistem/models"
+)
+
+// Izloženost (liability) je iznos koji kladionica isplaćuje ako ishod selekcije prođe.
+// Za svaki tiket na čekanju tabela ticket_liabilities čuva doprinos svake njegove
+// selekcije na čekanju: zbir mogućih dobitaka kombinacija na čekanju koje je sadrže.
+// Doprinos se ponovo računa u istoj transakciji u kojoj se tiket uplaćuje, obračunava
+// ili isplaćuje pre kraja, a izveštaji ga sabiraju po događaju i ishodu.
+
+type LiabilityService struct {
+	db *db.DBManager
+}
+
+func NewLiabilityService(db *db.DBManager) *LiabilityService {
+	return &LiabilityService{db: db}
+}
+
+// TopExposures vraća ishode sa najvećom izloženošću i događaje sa najvećim mogućim gubitkom.
+// Najgori slučaj događaja je zbir najveće izloženosti po tržištu (ishodi istog tržišta
+// se isključuju) umanjen za uloge tiketa koji sadrže taj događaj.
+func (ls *LiabilityService) TopExposures(limit int) (*models.LiabilityReport, error) {
+	report := &models.LiabilityReport{Outcomes: []models.OutcomeLiability{}, Events: []models.EventLiability{}}
+
+	rows, err := ls.db.Query(`SELECT eid, market_type, selected_outcome, SUM(liability), COUNT(DISTINCT ticket_id)
+             FROM ticket_liabilities GROUP BY eid, market_type, selected_outcome
+             ORDER BY SUM(liability) DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load outcome liabilities: %v", err)
+	}
+	for rows.Next() {
+		var o models.OutcomeLiability
+		if err := rows.Scan(&o.Eid, &o.MarketType, &o.SelectedOutcome, &o.Liability, &o.Tickets); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan outcome liability: %v", err)
+		}
+		report.Outcomes = append(report.Outcomes, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load outcome liabilities: %v", err)
+	}
+
+	rows, err = ls.db.Query(`WITH outcomes AS (
+                 SELECT eid, market_type, SUM(liability) AS liability
+                 FROM ticket_liabilities GROUP BY eid, market_type, selected_outcome
+             ), markets AS (
+                 SELECT eid, SUM(worst) AS worst FROM (
+                     SELECT eid, market_type, MAX(liability) AS worst FROM outcomes GROUP BY eid, market_type
+                 ) m GROUP BY eid
+             ), stakes AS (
+                 SELECT e.eid, SUM(t.total_stake) AS stake
+                 FROM (SELECT DISTINCT eid, ticket_id FROM ticket_liabilities) e
+                 JOIN tickets t ON t.ticket_id = e.ticket_id GROUP BY e.eid
+             )
+             SELECT m.eid, m.worst, s.stake FROM markets m JOIN stakes s ON s.eid = m.eid
+             ORDER BY m.worst - s.stake DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event liabilities: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e models.EventLiability
+		if err := rows.Scan(&e.Eid, &e.WorstCasePayout, &e.Stake); err != nil {
+			return nil, fmt.Errorf("failed to scan event liability: %v", err)
+		}
+		e.WorstCaseLoss = e.WorstCasePayout - e.Stake
+		report.Events = append(report.Events, e)
+	}
+	return report, rows.Err()
+}
+
+// refreshTicketLiability briše doprinos tiketa i, ako je tiket još na čekanju, upisuje ga
+// ponovo iz trenutnog stanja: iz redova kombinacija ili, za virtuelni tiket, analitički.
+func refreshTicketLiability(tx *sql.Tx, ticketID int) error {
+	if _, err := tx.Exec(`DELETE FROM ticket_liabilities WHERE ticket_id = $1`, ticketID); err != nil {
+		return fmt.Errorf("failed to clear liability of ticket %d: %v", ticketID, err)
+	}
+
+	var status, storage string
+	var systemCombination sql.NullString
+	var totalStake, payoutCap float64
+	var numCombinations int
+	if err := tx.QueryRow(`SELECT status, COALESCE(combination_storage, 'rows'), system_combination, total_stake,
+             num_combinations, COALESCE(payout_cap, 0) FROM tickets WHERE ticket_id = $1`, ticketID).Scan(&status, &storage,
+		&systemCombination, &totalStake, &numCombinations, &payoutCap); err != nil {
+		return fmt.Errorf("failed to load ticket %d: %v", ticketID, err)
+	}
+	if status != models.StatusPending {
+		return nil
+	}
+
+	if storage != models.StorageVirtual {
+		_, err := tx.Exec(`INSERT INTO ticket_liabilities (ticket_id, selection_id, eid, market_type, selected_outcome, liability)
+                 SELECT s.ticket_id, s.selection_id, s.eid, s.market_type, s.selected_outcome,
+                     CASE WHEN $2::float8 > 0 THEN LEAST(SUM(c.potential_win), $2::float8) ELSE SUM(c.potential_win) END
+                 FROM combinations c
+                 CROSS JOIN LATERAL unnest(c.selection_ids) AS cs(selection_id)
+                 JOIN selections s ON s.selection_id = cs.selection_id
+                 WHERE c.ticket_id = $1 AND c.status = $3 AND s.status = $3
+                 GROUP BY s.ticket_id, s.selection_id, s.eid, s.market_type, s.selected_outcome`,
+			ticketID, payoutCap, models.StatusPending)
+		if err != nil {
+			return fmt.Errorf("failed to record liability of ticket %d: %v", ticketID, err)
+		}
+		return nil
+	}
+
+	spec, err := calc.ParseSystem(systemCombination.String)
+	if err != nil || numCombinations <= 0 {
+		return fmt.Errorf("ticket %d has an invalid system: %v", ticketID, err)
+	}
+	selectionIDs, legs, err := loadTicketLegs(tx, ticketID)
+	if err != nil {
+		return err
+	}
+	exposure := calc.LegExposure(legs, spec, totalStake/float64(numCombinations))
+	for i, leg := range legs {
+		if leg.Status != models.StatusPending || exposure[i] <= 0 {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO ticket_liabilities (ticket_id, selection_id, eid, market_type, selected_outcome, liability)
+                 SELECT ticket_id, selection_id, eid, market_type, selected_outcome, $2 FROM selections WHERE selection_id = $1`,
+			selectionIDs[i], capPayout(exposure[i], payoutCap)); err != nil {
+			return fmt.Errorf("failed to record liability of ticket %d: %v", ticketID, err)
+		}
+	}
+	return nil
+}
+
+// outcomeLockSpace je prvi ključ advisory lock-a po ishodu; drugi je hashtext ishoda.
+const outcomeLockSpace = 7203115
+
+// checkOutcomeLiability odbija tiket ako bi izloženost nekog njegovog ishoda, zajedno sa
+// već uplaćenim tiketima, prešla MaxOutcomeLiability. Poziva se u transakciji uplate,
+// posle refreshTicketLiability, pa se doprinos tiketa čita iz ticket_liabilities. Za svaki
+// ishod se uzima advisory lock do kraja transakcije, pa dve uplate na isti ishod ne mogu
+// obe proći proveru; ključevi se zaključavaju rastuće da se uplate ne bi zaglavile.
+func (ts *TicketService) checkOutcomeLiability(tx *sql.Tx, ticketID int) error {
+	if ts.limits.MaxOutcomeLiability <= 0 {
+		return nil
+	}
+
+	type outcome struct {
+		eid, marketType, selectedOutcome string
+		key                              int32
+	}
+	rows, err := tx.Query(`SELECT eid, market_type, selected_outcome,
+                 hashtext(eid || chr(31) || market_type || chr(31) || selected_outcome) AS lock_key
+             FROM ticket_liabilities WHERE ticket_id = $1
+             GROUP BY eid, market_type, selected_outcome ORDER BY lock_key`, ticketID)
+	if err != nil {
+		return fmt.Errorf("failed to load liabilities of ticket %d: %v", ticketID, err)
+	}
+	var outcomes []outcome
+	for rows.Next() {
+		var o outcome
+		if err := rows.Scan(&o.eid, &o.marketType, &o.selectedOutcome, &o.key); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan liability of ticket %d: %v", ticketID, err)
+		}
+		outcomes = append(outcomes, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load liabilities of ticket %d: %v", ticketID, err)
+	}
+
+	for i, o := range outcomes {
+		if i == 0 || o.key != outcomes[i-1].key {
+			if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, outcomeLockSpace, o.key); err != nil {
+				return fmt.Errorf("failed to lock liability for event %s: %v", o.eid, err)
+			}
+		}
+	}
+	// Zbir se čita tek kada su svi ishodi zaključani: uključuje doprinos ovog tiketa i
+	// tiketa potvrđenih pre nego što je lock dobijen
+	for _, o := range outcomes {
+		var total float64
+		if err := tx.QueryRow(`SELECT COALESCE(SUM(liability), 0) FROM ticket_liabilities
+                 WHERE eid = $1 AND market_type = $2 AND selected_outcome = $3`,
+			o.eid, o.marketType, o.selectedOutcome).Scan(&total); err != nil {
+			return fmt.Errorf("failed to load liability for event %s: %v", o.eid, err)
+		}
+		if total > ts.limits.MaxOutcomeLiability {
+			return &LimitError{Limit: LimitMaxOutcomeLiability, Value: total, Allowed: ts.limits.MaxOutcomeLiability}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"goticketsistem/models"
+)
+
+var ErrLimitExceeded = errors.New("ticket limit exceeded")
+
+// Nazivi ograničenja koja se vraćaju klijentu u LimitError.
+const (
+	LimitMinStakePerCombination = "min_stake_per_combination"
+	LimitMaxTotalStake          = "max_total_stake"
+	LimitMaxPayout              = "max_payout"
+	LimitMaxSelections          = "max_selections"
+	LimitMaxCombinations        = "max_combinations"
+	LimitMaxOutcomeLiability    = "max_outcome_liability"
+	LimitMaxQuoteCombinations   = "max_quote_combinations"
+)
+
+// LimitError opisuje prekoračeno ograničenje: vrednost tiketa i dozvoljenu granicu.
+type LimitError struct {
+	Limit   string
+	Value   float64
+	Allowed float64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s is %g, allowed %g", ErrLimitExceeded, e.Limit, e.Value, e.Allowed)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// PlacementLimits su ograničenja uplate koja se proveravaju pre upisa tiketa.
+// Nulta vrednost polja znači da ograničenje ne postoji.
+type PlacementLimits struct {
+	MinStakePerCombination float64
+	MaxTotalStake          float64
+	MaxPayout              float64
+	// CapPayout umesto odbijanja tiketa ograničava njegovu isplatu na MaxPayout
+	CapPayout       bool
+	MaxSelections   int
+	MaxCombinations int
+	// MaxOutcomeLiability je najveća dozvoljena izloženost jednog ishoda događaja
+	MaxOutcomeLiability float64
+	// MaxQuoteCombinations je najveći broj kombinacija koje ponuda vraća kao listu
+	MaxQuoteCombinations int
+}
+
+var DefaultPlacementLimits = PlacementLimits{
+	MinStakePerCombination: 0.01,
+	MaxTotalStake:          100000,
+	MaxPayout:              1000000,
+	MaxSelections:          30,
+	MaxCombinations:        1000000,
+	MaxQuoteCombinations:   10000,
+}
+
+// Check proverava tiket iz zahteva redom od najjeftinijih provera: broj selekcija i ulog,
+// pa broj kombinacija, ulog po kombinaciji i isplata. Kada je isplata veća od MaxPayout,
+// a CapPayout je uključen, tiketu se postavlja PayoutCap umesto greške.
+func (l PlacementLimits) Check(ticket *models.Ticket) error {
+	if l.MaxSelections > 0 && len(ticket.Selections) > l.MaxSelections {
+		return &LimitError{Limit: LimitMaxSelections, Value: float64(len(ticket.Selections)), Allowed: float64(l.MaxSelections)}
+	}
+	if l.MaxTotalStake > 0 && ticket.TotalStake > l.MaxTotalStake {
+		return &LimitError{Limit: LimitMaxTotalStake, Value: ticket.TotalStake, Allowed: l.MaxTotalStake}
+	}
+
+	result, err := calculateTicket(ticket, false)
+	if err != nil {
+		return err
+	}
+	if l.MaxCombinations > 0 && result.NumCombinations > l.MaxCombinations {
+		return &LimitError{Limit: LimitMaxCombinations, Value: float64(result.NumCombinations), Allowed: float64(l.MaxCombinations)}
+	}
+	if result.StakePerCombination < l.MinStakePerCombination {
+		return &LimitError{Limit: LimitMinStakePerCombination, Value: result.StakePerCombination, Allowed: l.MinStakePerCombination}
+	}
+	if l.MaxPayout > 0 && result.MaxPayout > l.MaxPayout {
+		if !l.CapPayout {
+			return &LimitError{Limit: LimitMaxPayout, Value: result.MaxPayout, Allowed: l.MaxPayout}
+		}
+		ticket.PayoutCap = l.MaxPayout
+	}
+	return nil
+}
+
+// capPayout ograničava iznos na limit isplate tiketa; limit 0 znači bez ograničenja.
+func capPayout(amount, limit float64) float64 {
+	if limit > 0 && amount > limit {
+		return limit
+	}
+	return amount
+}
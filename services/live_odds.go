@@ -0,0 +1,86 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"goticketsistem/db"
+	"goticketsistem/models"
+	"log"
+	"time"
+)
+
+// Trenutne (live) kvote objavljuje servis kvota preko internog servera, a čuvaju se u
+// tabeli live_odds po ishodu (događaj, tržište, ishod). Isplata pre kraja ih čita samo
+// odatle, nikad iz zahteva igrača, pa igrač ne može da utiče na ponuđeni iznos.
+
+type LiveOddsService struct {
+	db *db.DBManager
+}
+
+func NewLiveOddsService(db *db.DBManager) *LiveOddsService {
+	return &LiveOddsService{db: db}
+}
+
+// Publish upisuje ili menja trenutne kvote datih ishoda i vraća broj upisanih kvota.
+func (ls *LiveOddsService) Publish(odds []models.LiveOdd) (int, error) {
+	for _, o := range odds {
+		if o.Eid == "" || o.MarketType == "" || o.SelectedOutcome == "" {
+			return 0, fmt.Errorf("%w: eid, market_type and selected_outcome are required", ErrInvalidLiveOdds)
+		}
+		if o.Odd <= 1 {
+			return 0, fmt.Errorf("%w: odd for %s %s %s must be greater than 1", ErrInvalidLiveOdds, o.Eid, o.MarketType, o.SelectedOutcome)
+		}
+	}
+
+	tx, err := ls.db.BeginTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	for _, o := range odds {
+		if _, err := tx.Exec(`INSERT INTO live_odds (eid, market_type, selected_outcome, odd, updated_at)
+                 VALUES ($1, $2, $3, $4, now())
+                 ON CONFLICT (eid, market_type, selected_outcome) DO UPDATE SET odd = EXCLUDED.odd, updated_at = EXCLUDED.updated_at`,
+			o.Eid, o.MarketType, o.SelectedOutcome, o.Odd); err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("failed to store live odds for event %s: %v", o.Eid, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	log.Printf("Published %d live odds", len(odds))
+	return len(odds), nil
+}
+
+// loadLiveOdds vraća trenutne kvote selekcija tiketa koje su još na čekanju, po ID-u
+// selekcije. Kvota koja nedostaje ili je starija od maxAge znači da ponuda trenutno nije
+// moguća (ErrCashOutUnavailable).
+func loadLiveOdds(tx *sql.Tx, ticketID int, maxAge time.Duration) (map[int]float64, error) {
+	rows, err := tx.Query(`SELECT s.selection_id, lo.odd, lo.updated_at
+             FROM selections s
+             LEFT JOIN live_odds lo ON lo.eid = s.eid AND lo.market_type = s.market_type AND lo.selected_outcome = s.selected_outcome
+             WHERE s.ticket_id = $1 AND s.status = $2`, ticketID, models.StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load live odds for ticket %d: %v", ticketID, err)
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	odds := make(map[int]float64)
+	for rows.Next() {
+		var id int
+		var odd sql.NullFloat64
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&id, &odd, &updatedAt); err != nil {
+			return nil, err
+		}
+		if !odd.Valid {
+			return nil, fmt.Errorf("%w: no live odds for selection %d", ErrCashOutUnavailable, id)
+		}
+		if maxAge > 0 && now.Sub(updatedAt.Time) > maxAge {
+			return nil, fmt.Errorf("%w: live odds for selection %d are older than %s", ErrCashOutUnavailable, id, maxAge)
+		}
+		odds[id] = odd.Float64
+	}
+	return odds, rows.Err()
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/models"
+	"log"
+	"strings"
+)
+
+// RecomputeMinPayouts ponovo računa min_payout sačuvanih tiketa po definiciji iz
+// calc.Result: najmanja isplata među ishodima u kojima tiket nešto dobija. Raniji upisi
+// su za sistem čuvali najmanju pojedinačnu kombinaciju, a za normalni tiket 0.
+// Tiketi sa isplatom pre kraja se preskaču jer im je ulog po kombinaciji promenjen.
+// Svaki tiket se ažurira u svojoj transakciji, pa se komanda može ponoviti. Tiketi čiji
+// se sistem ne može protumačiti se preskaču i navode u izveštaju umesto da prekinu obradu.
+func (ts *TicketService) RecomputeMinPayouts() (*RecomputeReport, error) {
+	rows, err := ts.db.Query(`SELECT ticket_id FROM tickets
+             WHERE status <> $1 AND COALESCE(cashed_out_amount, 0) = 0 ORDER BY ticket_id`, models.StatusCashedOut)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %v", err)
+	}
+	var ticketIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan ticket id: %v", err)
+		}
+		ticketIDs = append(ticketIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %v", err)
+	}
+
+	report := &RecomputeReport{Checked: len(ticketIDs)}
+	for _, ticketID := range ticketIDs {
+		changed, err := ts.recomputeMinPayout(ticketID)
+		if errors.Is(err, calc.ErrInvalidSystem) || errors.Is(err, errNoSelections) {
+			log.Printf("Ticket %d skipped: %v", ticketID, err)
+			report.Skipped = append(report.Skipped, SkippedTicket{TicketID: ticketID, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("ticket %d: %v", ticketID, err)
+		}
+		if changed {
+			report.Updated = append(report.Updated, ticketID)
+		}
+	}
+	log.Printf("Recomputed min_payout for %d of %d tickets, %d skipped", len(report.Updated), report.Checked, len(report.Skipped))
+	return report, nil
+}
+
+// RecomputeReport je rezultat ponovnog računanja min_payout: broj pregledanih tiketa,
+// ažurirani tiketi i tiketi koji su preskočeni uz razlog.
+type RecomputeReport struct {
+	Checked int
+	Updated []int
+	Skipped []SkippedTicket
+}
+
+// SkippedTicket je tiket koji nije obrađen i greška zbog koje je preskočen.
+type SkippedTicket struct {
+	TicketID int
+	Error    string
+}
+
+// systemPlanForStored pravi plan sačuvanog sistema. Pre tumačenja "k od n uključujući f
+// fiksnih" specifikacija je brojala samo slobodne selekcije (n slobodnih, k slobodnih u
+// kombinaciji), pa se takav zapis tiketa sa fiksnim selekcijama prevodi dodavanjem f i
+// na n i na svaku veličinu. Sačuvani broj kombinacija mora da se slaže sa planom.
+func systemPlanForStored(legs []calc.Leg, stored string, totalStake float64, numCombinations int64) (*calc.SystemPlan, error) {
+	spec, err := storedSystemSpec(legs, stored)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := calc.NewSystemPlan(legs, spec, totalStake)
+	if err != nil {
+		return nil, err
+	}
+	if numCombinations > 0 && int64(plan.NumCombinations) != numCombinations {
+		return nil, fmt.Errorf("%w: system %q gives %d combinations, ticket has %d stored",
+			calc.ErrInvalidSystem, stored, plan.NumCombinations, numCombinations)
+	}
+	return plan, nil
+}
+
+// storedSystemSpec čita sačuvanu specifikaciju sistema u današnjem značenju, prevodeći
+// stari zapis tiketa sa fiksnim selekcijama (vidi systemPlanForStored).
+func storedSystemSpec(legs []calc.Leg, stored string) (calc.SystemSpec, error) {
+	fixed := 0
+	for _, leg := range legs {
+		if leg.Fixed {
+			fixed++
+		}
+	}
+	spec, err := calc.ParseSystem(stored)
+	if err != nil && fixed > 0 {
+		// Stari zapis poput "1/4+2F" sada ne prolazi jer je k manje od f; veličine se
+		// čitaju bez dela "+fF", a broj fiksnih se uzima iz selekcija
+		if sizes, _, ok := strings.Cut(stored, "+"); ok {
+			if legacy, legacyErr := calc.ParseSystem(sizes); legacyErr == nil && legacy.N == len(legs)-fixed {
+				spec, err = legacy, nil
+			}
+		}
+	}
+	if err != nil {
+		return calc.SystemSpec{}, err
+	}
+	if fixed > 0 && spec.N == len(legs)-fixed {
+		legacy := calc.SystemSpec{N: spec.N + fixed, Fixed: fixed, FixedGiven: true}
+		for _, k := range spec.Sizes {
+			legacy.Sizes = append(legacy.Sizes, k+fixed)
+		}
+		spec = legacy
+	}
+	return spec, nil
+}
+
+func (ts *TicketService) recomputeMinPayout(ticketID int) (bool, error) {
+	tx, err := ts.db.BeginTransaction()
+	if err != nil {
+		return false, err
+	}
+
+	var systemCombination sql.NullString
+	var totalStake, minPayout, payoutCap float64
+	var numCombinations int64
+	err = tx.QueryRow(`SELECT system_combination, total_stake, min_payout, COALESCE(payout_cap, 0), num_combinations
+             FROM tickets WHERE ticket_id = $1 FOR UPDATE`, ticketID).Scan(&systemCombination, &totalStake, &minPayout, &payoutCap, &numCombinations)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	_, legs, err := loadTicketLegs(tx, ticketID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if len(legs) == 0 {
+		tx.Rollback()
+		return false, errNoSelections
+	}
+	// Iznos se računa kao pri uplati: obračunate selekcije se vraćaju na čekanje, a
+	// poništene ostaju poništene jer ulaze kvotom 1.0
+	for i := range legs {
+		if legs[i].Status != models.StatusVoid {
+			legs[i].Status = models.StatusPending
+		}
+	}
+
+	var result calc.Result
+	if systemCombination.Valid && systemCombination.String != "" {
+		plan, err := systemPlanForStored(legs, systemCombination.String, totalStake, numCombinations)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		result = plan.Summary()
+	} else {
+		result = calc.Single(legs, totalStake)
+	}
+
+	result.MinPayout = capPayout(result.MinPayout, payoutCap)
+
+	if result.MinPayout == minPayout {
+		tx.Rollback()
+		return false, nil
+	}
+	if _, err := tx.Exec(`UPDATE tickets SET min_payout = $1 WHERE ticket_id = $2`, result.MinPayout, ticketID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"goticketsistem/calc"
+	"goticketsistem/models"
+)
+
+func TestSystemPlanForStored(t *testing.T) {
+	legs := func(free, fixed int) []calc.Leg {
+		var out []calc.Leg
+		for i := 0; i < fixed; i++ {
+			out = append(out, calc.Leg{Odd: 2, Fixed: true, Status: models.StatusPending})
+		}
+		for i := 0; i < free; i++ {
+			out = append(out, calc.Leg{Odd: 1.5 + float64(i), Status: models.StatusPending})
+		}
+		return out
+	}
+	tests := []struct {
+		name            string
+		legs            []calc.Leg
+		stored          string
+		numCombinations int64
+		want            int
+	}{
+		{"current spec", legs(4, 0), "2/4", 6, 6},
+		{"current spec with bankers", legs(4, 1), "3/5+1F", 6, 6},
+		// Pre tumačenja "k od n uključujući f" k i n su brojali samo slobodne selekcije
+		{"legacy spec with bankers", legs(4, 1), "2/4", 6, 6},
+		{"legacy spec with explicit bankers", legs(4, 2), "1/4+2F", 4, 4},
+		{"legacy multi-size spec", legs(3, 1), "1,2/3", 6, 6},
+		{"unknown stored count", legs(4, 1), "2/4", 0, 6},
+	}
+	for _, tt := range tests {
+		plan, err := systemPlanForStored(tt.legs, tt.stored, 10, tt.numCombinations)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if plan.NumCombinations != tt.want {
+			t.Errorf("%s: %d combinations, want %d", tt.name, plan.NumCombinations, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		name            string
+		legs            []calc.Leg
+		stored          string
+		numCombinations int64
+	}{
+		{"unparseable", legs(4, 0), "2of4", 6},
+		{"wrong selection count", legs(3, 0), "2/4", 6},
+		{"stored count mismatch", legs(4, 0), "2/4", 4},
+	} {
+		if _, err := systemPlanForStored(tt.legs, tt.stored, 10, tt.numCombinations); !errors.Is(err, calc.ErrInvalidSystem) {
+			t.Errorf("%s: error = %v, want ErrInvalidSystem", tt.name, err)
+		}
+	}
+}
+
+func TestStoredSystemSpec(t *testing.T) {
+	legs := func(free, fixed int) []calc.Leg {
+		out := make([]calc.Leg, free+fixed)
+		for i := range out {
+			out[i] = calc.Leg{Odd: 2, Fixed: i < fixed, Status: models.StatusPending}
+		}
+		return out
+	}
+	tests := []struct {
+		legs   []calc.Leg
+		stored string
+		want   string
+	}{
+		{legs(4, 0), "2/4", "2/4"},
+		{legs(4, 1), "3/5+1F", "3/5+1F"},
+		{legs(4, 1), "2/4", "3/5+1F"},
+		{legs(4, 2), "1/4+2F", "3/6+2F"},
+		{legs(3, 1), "1,2/3", "2,3/4+1F"},
+		{legs(4, 0), "Yankee", "Yankee"},
+	}
+	for _, tt := range tests {
+		spec, err := storedSystemSpec(tt.legs, tt.stored)
+		if err != nil {
+			t.Errorf("storedSystemSpec(%q): %v", tt.stored, err)
+			continue
+		}
+		if got := spec.String(); got != tt.want {
+			t.Errorf("storedSystemSpec(%q) = %q, want %q", tt.stored, got, tt.want)
+		}
+	}
+	if _, err := storedSystemSpec(legs(4, 1), "2of4"); !errors.Is(err, calc.ErrInvalidSystem) {
+		t.Errorf("storedSystemSpec(\"2of4\") error = %v, want ErrInvalidSystem", err)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/models"
+)
+
+// QuoteTicket računa broj kombinacija, ulog po kombinaciji i minimalnu/maksimalnu isplatu
+// tiketa isključivo u memoriji, istom matematikom i istim limitima kao pri uplati, bez
+// upisa u bazu. Lista kombinacija se vraća samo do MaxQuoteCombinations; veći sistemi se
+// posle uplate listaju po stranicama. Selekcije u kombinacijama se označavaju pozicijom
+// u ticket.Selections.
+func (ts *TicketService) QuoteTicket(ticket *models.Ticket, includeCombinations bool) (*models.TicketQuote, error) {
+	if err := ts.limits.Check(ticket); err != nil {
+		return nil, err
+	}
+	result, err := calculateTicket(ticket, false)
+	if err != nil {
+		return nil, err
+	}
+	if includeCombinations {
+		if limit := ts.limits.MaxQuoteCombinations; limit > 0 && result.NumCombinations > limit {
+			return nil, &LimitError{Limit: LimitMaxQuoteCombinations, Value: float64(result.NumCombinations), Allowed: float64(limit)}
+		}
+		if result, err = calculateTicket(ticket, true); err != nil {
+			return nil, err
+		}
+	}
+
+	quote := &models.TicketQuote{
+		NumCombinations:     result.NumCombinations,
+		StakePerCombination: result.StakePerCombination,
+		MinPayout:           capPayout(result.MinPayout, ticket.PayoutCap),
+		MaxPayout:           capPayout(result.MaxPayout, ticket.PayoutCap),
+	}
+	if includeCombinations {
+		quote.Combinations = make([]models.QuoteCombination, len(result.Combinations))
+		for i, c := range result.Combinations {
+			quote.Combinations[i] = models.QuoteCombination{SelectionIndexes: c.Legs, Odds: c.Odds, PotentialWin: c.PotentialWin}
+		}
+	}
+	return quote, nil
+}
+
+// PayoutTable za sistemski tiket iz zahteva vraća najmanju i najveću isplatu za svaki broj
+// dobitnih slobodnih selekcija, uz pretpostavku da prolaze sve fiksne selekcije.
+func (ts *TicketService) PayoutTable(ticket *models.Ticket) (*models.PayoutTable, error) {
+	if ticket.TicketType != "system" || ticket.SystemCombination == "" {
+		return nil, fmt.Errorf("%w: payout table requires a system ticket", calc.ErrInvalidSystem)
+	}
+	spec, err := calc.ParseSystem(ticket.SystemCombination)
+	if err != nil {
+		return nil, err
+	}
+	legs := ticketLegs(ticket)
+	plan, err := calc.NewSystemPlan(legs, spec, ticket.TotalStake)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &models.PayoutTable{
+		NumCombinations:     plan.NumCombinations,
+		StakePerCombination: plan.StakePerCombination,
+	}
+	for _, leg := range legs {
+		if leg.Fixed {
+			table.FixedSelections++
+		} else {
+			table.FreeSelections++
+		}
+	}
+	table.BankersMustWin = table.FixedSelections > 0
+	for _, row := range plan.PayoutTable() {
+		table.Rows = append(table.Rows, models.WinnersPayout{
+			Winners:             row.Winners,
+			WinningCombinations: row.WinningCombinations,
+			MinPayout:           row.MinPayout,
+			MaxPayout:           row.MaxPayout,
+		})
+	}
+	return table, nil
+}
+
+// ticketLegs pretvara selekcije iz zahteva u noge za paket calc, istim redosledom.
+func ticketLegs(ticket *models.Ticket) []calc.Leg {
+	legs := make([]calc.Leg, len(ticket.Selections))
+	for i, sel := range ticket.Selections {
+		legs[i] = calc.Leg{Odd: sel.OddValue, Fixed: sel.IsFixed, Status: initialSelectionStatus(sel)}
+	}
+	return legs
+}
+
+// calculateTicket računa tiket iz zahteva pomoću paketa calc. Lista kombinacija sistemskog
+// tiketa se pravi samo kada je tražena, inače se isplate računaju u zatvorenom obliku.
+func calculateTicket(ticket *models.Ticket, includeCombinations bool) (calc.Result, error) {
+	legs := ticketLegs(ticket)
+	if ticket.TicketType != "system" || ticket.SystemCombination == "" {
+		return calc.Single(legs, ticket.TotalStake), nil
+	}
+	spec, err := calc.ParseSystem(ticket.SystemCombination)
+	if err != nil {
+		return calc.Result{}, err
+	}
+	if includeCombinations {
+		return calc.System(legs, spec, ticket.TotalStake)
+	}
+	plan, err := calc.NewSystemPlan(legs, spec, ticket.TotalStake)
+	if err != nil {
+		return calc.Result{}, err
+	}
+	return plan.Summary(), nil
+}
+
+// ValidateSystem proverava specifikaciju sistema prema selekcijama tiketa pre bilo kakvog upisa.
+// Za tikete koji nisu sistemski ne radi ništa.
+func ValidateSystem(ticket *models.Ticket) error {
+	if ticket.TicketType != "system" || ticket.SystemCombination == "" {
+		return nil
+	}
+	spec, err := calc.ParseSystem(ticket.SystemCombination)
+	if err != nil {
+		return err
+	}
+	var free, fixed int
+	for _, sel := range ticket.Selections {
+		if sel.IsFixed {
+			fixed++
+		} else {
+			free++
+		}
+	}
+	return spec.Validate(free, fixed)
+}
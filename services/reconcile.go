@@ -0,0 +1,170 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/models"
+	"log"
+)
+
+var errNoSelections = errors.New("ticket has no selections")
+
+// ReconcileReport je rezultat provere polu-upisanih tiketa.
+type ReconcileReport struct {
+	Found    []int
+	Repaired []int
+	Removed  []int
+}
+
+// halfWrittenCondition prepoznaje tiket koji je upisan bez kombinacija i iznosa: ranije se
+// uplata radila u dve transakcije, pa je greška u drugoj ostavljala samo tiket i selekcije.
+const halfWrittenCondition = `t.status = 'pending' AND (t.num_combinations = 0
+             OR (COALESCE(t.combination_storage, 'rows') = 'rows'
+                 AND NOT EXISTS (SELECT 1 FROM combinations c WHERE c.ticket_id = t.ticket_id)))`
+
+// ReconcileTickets pronalazi polu-upisane tikete i popravlja ih: kombinacije i iznosi se
+// ponovo računaju iz sačuvanih selekcija, a tiket koji ne može da se obradi (npr. zbog
+// neispravnog sistema) briše se zajedno sa selekcijama, jer njegova uplata klijentu
+// nikada nije potvrđena. Uz dryRun samo vraća pronađene tikete.
+func (ts *TicketService) ReconcileTickets(dryRun bool) (*ReconcileReport, error) {
+	rows, err := ts.db.Query(`SELECT t.ticket_id FROM tickets t WHERE ` + halfWrittenCondition + ` ORDER BY t.ticket_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find half-written tickets: %v", err)
+	}
+	report := &ReconcileReport{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan ticket id: %v", err)
+		}
+		report.Found = append(report.Found, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find half-written tickets: %v", err)
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	for _, ticketID := range report.Found {
+		repaired, err := ts.repairTicket(ticketID)
+		if errors.Is(err, calc.ErrInvalidSystem) || errors.Is(err, errNoSelections) {
+			log.Printf("Ticket %d cannot be repaired, removing it: %v", ticketID, err)
+			if err := ts.removeTicket(ticketID); err != nil {
+				return report, err
+			}
+			report.Removed = append(report.Removed, ticketID)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("ticket %d: %v", ticketID, err)
+		}
+		if repaired {
+			report.Repaired = append(report.Repaired, ticketID)
+		}
+	}
+	log.Printf("Reconciled tickets: %d found, %d repaired, %d removed", len(report.Found), len(report.Repaired), len(report.Removed))
+	return report, nil
+}
+
+// repairTicket ponovo obrađuje tiket iz sačuvanih podataka. Vraća false ako ga je u
+// međuvremenu popravio neko drugi.
+func (ts *TicketService) repairTicket(ticketID int) (bool, error) {
+	tx, err := ts.db.BeginTransaction()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	var ticket models.Ticket
+	var systemCombination sql.NullString
+	var payoutCap sql.NullFloat64
+	err = tx.QueryRow(`SELECT t.total_stake, t.ticket_type, t.system_combination, COALESCE(t.combination_storage, 'rows'), t.payout_cap
+             FROM tickets t WHERE t.ticket_id = $1 AND `+halfWrittenCondition+` FOR UPDATE`, ticketID).Scan(&ticket.TotalStake,
+		&ticket.TicketType, &systemCombination, &ticket.CombinationStorage, &payoutCap)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return false, nil
+	}
+	if err != nil {
+		tx.Rollback()
+		return false, fmt.Errorf("failed to load ticket %d: %v", ticketID, err)
+	}
+	ticket.SystemCombination = systemCombination.String
+	ticket.PayoutCap = payoutCap.Float64
+
+	var selections int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM selections WHERE ticket_id = $1`, ticketID).Scan(&selections); err != nil {
+		tx.Rollback()
+		return false, fmt.Errorf("failed to count selections of ticket %d: %v", ticketID, err)
+	}
+	if selections == 0 {
+		tx.Rollback()
+		return false, errNoSelections
+	}
+	if ticket.TicketType == "system" && ticket.SystemCombination != "" {
+		// Stari tiket sa fiksnim selekcijama čuva sistem u ranijem značenju; prevodi se i
+		// upisuje u današnjem, a tiket se briše samo ako ni stari zapis nema smisla
+		_, legs, err := loadTicketLegs(tx, ticketID)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		spec, err := storedSystemSpec(legs, ticket.SystemCombination)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if current := spec.String(); current != ticket.SystemCombination {
+			if _, err := tx.Exec(`UPDATE tickets SET system_combination = $1 WHERE ticket_id = $2`, current, ticketID); err != nil {
+				tx.Rollback()
+				return false, fmt.Errorf("failed to update system of ticket %d: %v", ticketID, err)
+			}
+			log.Printf("Ticket %d: system %q read as %q", ticketID, ticket.SystemCombination, current)
+			ticket.SystemCombination = current
+		}
+	}
+
+	if err := ts.processTicket(tx, ticketID, &ticket); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit ticket %d: %v", ticketID, err)
+	}
+	return true, nil
+}
+
+// removeTicket briše polu-upisan tiket sa svim njegovim redovima, ako je i dalje polu-upisan.
+func (ts *TicketService) removeTicket(ticketID int) error {
+	tx, err := ts.db.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	var id int
+	err = tx.QueryRow(`SELECT t.ticket_id FROM tickets t WHERE t.ticket_id = $1 AND `+halfWrittenCondition+` FOR UPDATE`,
+		ticketID).Scan(&id)
+	if err == sql.ErrNoRows {
+		tx.Rollback()
+		return nil
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to lock ticket %d: %v", ticketID, err)
+	}
+	for _, stmt := range []string{
+		`DELETE FROM ticket_liabilities WHERE ticket_id = $1`,
+		`DELETE FROM combinations WHERE ticket_id = $1`,
+		`DELETE FROM selections WHERE ticket_id = $1`,
+		`DELETE FROM tickets WHERE ticket_id = $1`,
+	} {
+		if _, err := tx.Exec(stmt, ticketID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to remove ticket %d: %v", ticketID, err)
+		}
+	}
+	return tx.Commit()
+}
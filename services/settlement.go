@@ -0,0 +1,397 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/db"
+	"goticketsistem/grading"
+	"goticketsistem/models"
+	"log"
+
+	"github.com/lib/pq"
+)
+
+var ErrSelectionNotFound = errors.New("selection not found")
+
+type SettlementService struct {
+	db      *db.DBManager
+	graders *grading.Registry
+}
+
+func NewSettlementService(db *db.DBManager) *SettlementService {
+	return &SettlementService{db: db, graders: grading.DefaultRegistry()}
+}
+
+// Graders vraća registar pravila ocenjivanja, kako bi se mogla dodati pravila za nove tipove igara.
+func (ss *SettlementService) Graders() *grading.Registry {
+	return ss.graders
+}
+
+// SettleSelections upisuje ishode selekcija i u istoj transakciji ponovo obračunava
+// sve kombinacije i tikete koji ih sadrže.
+func (ss *SettlementService) SettleSelections(results []models.SelectionResult) ([]int, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results provided")
+	}
+	for _, res := range results {
+		if !models.IsSettlementStatus(res.Status) {
+			return nil, fmt.Errorf("invalid status %q for selection %d", res.Status, res.SelectionID)
+		}
+	}
+
+	tx, err := ss.db.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	ticketIDs, err := applySelectionResults(tx, results)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ticketIDs, nil
+}
+
+// SettleEvent ocenjuje sve selekcije na čekanju za dati događaj i tip igre, bez obzira
+// na tiket kome pripadaju, i obračunava pogođene tikete u jednoj transakciji. Selekcija
+// koja se ne može oceniti (npr. pogrešno upisan ishod) ostaje na čekanju i vraća se u
+// odgovoru, a ostale se obračunavaju.
+func (ss *SettlementService) SettleEvent(event models.EventResult) (*models.EventSettlementResponse, error) {
+	result, err := grading.ParseResult(event.Result, event.Periods)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := ss.db.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+
+	rows, err := tx.Query(`SELECT selection_id, sport_type, selected_outcome FROM selections
+             WHERE eid = $1 AND market_type = $2 AND status = $3 FOR UPDATE`,
+		event.Eid, event.MarketType, models.StatusPending)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to load selections for event %s: %v", event.Eid, err)
+	}
+	res := &models.EventSettlementResponse{TicketIDs: []int{}, Ungraded: []models.UngradedSelection{}}
+	var results []models.SelectionResult
+	for rows.Next() {
+		var id int
+		var sportType, outcome string
+		if err := rows.Scan(&id, &sportType, &outcome); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		status, err := ss.graders.Grade(sportType, event.MarketType, outcome, result)
+		if errors.Is(err, grading.ErrInvalidOutcome) || errors.Is(err, grading.ErrUnsupportedMarket) {
+			log.Printf("Skipping selection %d of event %s: %v", id, event.Eid, err)
+			res.Ungraded = append(res.Ungraded, models.UngradedSelection{SelectionID: id, Error: err.Error()})
+			continue
+		}
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to grade selection %d: %w", id, err)
+		}
+		results = append(results, models.SelectionResult{SelectionID: id, Status: status})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		tx.Rollback()
+		log.Printf("No gradable pending selections for event %s, market %s", event.Eid, event.MarketType)
+		return res, nil
+	}
+
+	ticketIDs, err := applySelectionResults(tx, results)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	log.Printf("Settled event %s, market %s, result %s: %d selections, %d tickets, %d ungraded",
+		event.Eid, event.MarketType, event.Result, len(results), len(ticketIDs), len(res.Ungraded))
+	res.SettledSelections = len(results)
+	if ticketIDs != nil {
+		res.TicketIDs = ticketIDs
+	}
+	return res, nil
+}
+
+// applySelectionResults upisuje statuse selekcija i obračunava tikete kojima pripadaju.
+func applySelectionResults(tx *sql.Tx, results []models.SelectionResult) ([]int, error) {
+	selectionIDs := make([]int64, 0, len(results))
+	for _, res := range results {
+		result, err := tx.Exec(`UPDATE selections SET status = $1 WHERE selection_id = $2`, res.Status, res.SelectionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update selection %d: %v", res.SelectionID, err)
+		}
+		if n, err := result.RowsAffected(); err == nil && n == 0 {
+			return nil, fmt.Errorf("%w: %d", ErrSelectionNotFound, res.SelectionID)
+		}
+		selectionIDs = append(selectionIDs, int64(res.SelectionID))
+	}
+
+	ticketIDs, err := affectedTickets(tx, selectionIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := settleTickets(tx, ticketIDs); err != nil {
+		return nil, err
+	}
+	return ticketIDs, nil
+}
+
+// settleCombinationRows obračunava svaku sačuvanu kombinaciju tiketa i vraća status i isplatu tiketa.
+func settleCombinationRows(tx *sql.Tx, ticketID int, legs map[int64]calc.Leg) (string, float64, error) {
+	combos, err := loadCombinationRows(tx, ticketID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var finalPayout float64
+	var pendingCombos, voidCombos int
+	for _, c := range combos {
+		status, payout, potentialWin := calc.SettleCombination(combinationLegs(c.selectionIDs, legs), c.stake)
+		switch status {
+		case models.StatusPending:
+			pendingCombos++
+		case models.StatusVoid:
+			voidCombos++
+		}
+		finalPayout += payout
+		if _, err := tx.Exec(`UPDATE combinations SET status = $1, final_payout = $2, potential_win = $3 WHERE combination_id = $4`,
+			status, payout, potentialWin, c.id); err != nil {
+			return "", 0, fmt.Errorf("failed to update combination %d: %v", c.id, err)
+		}
+	}
+	return calc.TicketStatus(len(combos), pendingCombos, voidCombos, finalPayout), finalPayout, nil
+}
+
+// settleVirtualSystem obračunava sistemski tiket bez sačuvanih kombinacija, analitički iz selekcija i sistema.
+func settleVirtualSystem(tx *sql.Tx, ticketID int, systemCombination string, stakePerCombination float64) (string, float64, error) {
+	spec, err := calc.ParseSystem(systemCombination)
+	if err != nil {
+		return "", 0, fmt.Errorf("ticket %d: %v", ticketID, err)
+	}
+	_, legs, err := loadTicketLegs(tx, ticketID)
+	if err != nil {
+		return "", 0, err
+	}
+	outcome := calc.SettleSystem(legs, spec, stakePerCombination)
+	log.Printf("Virtual settlement of ticket %d: %d combinations, %d pending, payout %f",
+		ticketID, outcome.NumCombinations, outcome.PendingCombinations, outcome.Payout)
+	return outcome.Status, outcome.Payout, nil
+}
+
+func affectedTickets(tx *sql.Tx, selectionIDs []int64) ([]int, error) {
+	rows, err := tx.Query(`SELECT DISTINCT ticket_id FROM selections WHERE selection_id = ANY($1) ORDER BY ticket_id`, pq.Array(selectionIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load affected tickets: %v", err)
+	}
+	defer rows.Close()
+
+	var ticketIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ticketIDs = append(ticketIDs, id)
+	}
+	return ticketIDs, rows.Err()
+}
+
+// settleTickets pre obračuna zaključava sve tikete, pa sve račune njihovih igrača, oba
+// redom po id-u. Obračun tiketa knjiži isplatu na račun igrača, pa bi dva obračuna čiji
+// tiketi dele igrače inače mogla zaključati iste račune obrnutim redom.
+func settleTickets(tx *sql.Tx, ticketIDs []int) error {
+	if err := lockTickets(tx, ticketIDs); err != nil {
+		return err
+	}
+	if err := lockTicketAccounts(tx, ticketIDs); err != nil {
+		return err
+	}
+	for _, ticketID := range ticketIDs {
+		if err := settleTicket(tx, ticketID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func lockTickets(tx *sql.Tx, ticketIDs []int) error {
+	rows, err := tx.Query(`SELECT ticket_id FROM tickets WHERE ticket_id = ANY($1) ORDER BY ticket_id FOR UPDATE`, pq.Array(ticketIDs))
+	if err != nil {
+		return fmt.Errorf("failed to lock tickets: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// settleTicket ponovo obračunava sve kombinacije tiketa, ažurira brojače, status i isplatu
+// tiketa i knjiži promenu isplate na račun igrača.
+func settleTicket(tx *sql.Tx, ticketID int) error {
+	// Zaključavamo tiket da paralelni obračuni ne bi prepisali jedan drugog
+	var ticketStatus, storage string
+	var systemCombination sql.NullString
+	var totalStake, payoutCap float64
+	var numCombinations int
+	if err := tx.QueryRow(`SELECT status, system_combination, COALESCE(combination_storage, 'rows'), total_stake, num_combinations,
+             COALESCE(payout_cap, 0) FROM tickets WHERE ticket_id = $1 FOR UPDATE`, ticketID).Scan(&ticketStatus, &systemCombination,
+		&storage, &totalStake, &numCombinations, &payoutCap); err != nil {
+		return fmt.Errorf("failed to lock ticket %d: %v", ticketID, err)
+	}
+	if ticketStatus == models.StatusCashedOut {
+		log.Printf("Ticket %d is cashed out, skipping settlement", ticketID)
+		return nil
+	}
+
+	legs, err := loadSettlementLegs(tx, ticketID)
+	if err != nil {
+		return err
+	}
+
+	var hits, misses, pending int
+	for _, leg := range legs {
+		switch leg.Status {
+		case models.StatusWon, models.StatusHalfWon:
+			hits++
+		case models.StatusLost, models.StatusHalfLost:
+			misses++
+		case models.StatusPending:
+			pending++
+		}
+	}
+
+	var status string
+	var finalPayout float64
+	if storage == models.StorageVirtual {
+		if numCombinations <= 0 {
+			return fmt.Errorf("ticket %d has no combinations", ticketID)
+		}
+		status, finalPayout, err = settleVirtualSystem(tx, ticketID, systemCombination.String, totalStake/float64(numCombinations))
+	} else {
+		status, finalPayout, err = settleCombinationRows(tx, ticketID, legs)
+	}
+	if err != nil {
+		return err
+	}
+
+	if status == models.StatusPending {
+		finalPayout = 0
+	} else if systemCombination.Valid {
+		finalPayout = applySystemBonus(ticketID, systemCombination.String, legs, finalPayout)
+	}
+	finalPayout = capPayout(finalPayout, payoutCap)
+	if _, err := tx.Exec(`UPDATE tickets SET hits = $1, misses = $2, pending = $3, status = $4, final_payout = $5 WHERE ticket_id = $6`,
+		hits, misses, pending, status, finalPayout, ticketID); err != nil {
+		return fmt.Errorf("failed to update ticket %d: %v", ticketID, err)
+	}
+	if err := creditTicketPayout(tx, ticketID, status, finalPayout); err != nil {
+		return err
+	}
+	if err := refreshTicketLiability(tx, ticketID); err != nil {
+		return err
+	}
+
+	log.Printf("Settled ticket %d: status=%s, hits=%d, misses=%d, pending=%d, final_payout=%f",
+		ticketID, status, hits, misses, pending, finalPayout)
+	return nil
+}
+
+type combinationRow struct {
+	id           int
+	selectionIDs []int64
+	stake        float64
+	potentialWin float64
+	status       string
+	finalPayout  float64
+}
+
+func loadCombinationRows(tx *sql.Tx, ticketID int) ([]combinationRow, error) {
+	rows, err := tx.Query(`SELECT combination_id, selection_ids, stake_per_combination, potential_win, status, COALESCE(final_payout, 0)
+             FROM combinations WHERE ticket_id = $1 ORDER BY combination_id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load combinations for ticket %d: %v", ticketID, err)
+	}
+	defer rows.Close()
+
+	var combos []combinationRow
+	for rows.Next() {
+		var c combinationRow
+		var ids pq.Int64Array
+		if err := rows.Scan(&c.id, &ids, &c.stake, &c.potentialWin, &c.status, &c.finalPayout); err != nil {
+			return nil, err
+		}
+		c.selectionIDs = ids
+		combos = append(combos, c)
+	}
+	return combos, rows.Err()
+}
+
+func loadSettlementLegs(tx *sql.Tx, ticketID int) (map[int64]calc.Leg, error) {
+	rows, err := tx.Query(`SELECT selection_id, odd_value, status FROM selections WHERE ticket_id = $1`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load selections for ticket %d: %v", ticketID, err)
+	}
+	defer rows.Close()
+
+	legs := make(map[int64]calc.Leg)
+	for rows.Next() {
+		var id int64
+		var leg calc.Leg
+		if err := rows.Scan(&id, &leg.Odd, &leg.Status); err != nil {
+			return nil, err
+		}
+		legs[id] = leg
+	}
+	return legs, rows.Err()
+}
+
+// applySystemBonus dodaje bonus imenovanog sistema (npr. Lucky 15) na konačnu isplatu tiketa.
+func applySystemBonus(ticketID int, systemCombination string, legs map[int64]calc.Leg, finalPayout float64) float64 {
+	spec, err := calc.ParseSystem(systemCombination)
+	if err != nil || spec.Name == "" {
+		return finalPayout
+	}
+	all := make([]calc.Leg, 0, len(legs))
+	for _, leg := range legs {
+		all = append(all, leg)
+	}
+	withBonus := calc.SystemBonus(spec, all, finalPayout)
+	if withBonus != finalPayout {
+		log.Printf("Applied %s bonus to ticket %d: %f -> %f", spec.Name, ticketID, finalPayout, withBonus)
+	}
+	return withBonus
+}
+
+// combinationLegs vraća noge kombinacije; selekcija koje nema u mapi smatra se nerešenom.
+func combinationLegs(selectionIDs []int64, legs map[int64]calc.Leg) []calc.Leg {
+	out := make([]calc.Leg, len(selectionIDs))
+	for i, id := range selectionIDs {
+		leg, ok := legs[id]
+		if !ok {
+			leg.Status = models.StatusPending
+		}
+		out[i] = leg
+	}
+	return out
+}
@@ -1,54 +1,69 @@
 package services
 
 import (
+	"database/sql"
 	"fmt"
+	"goticketsistem/calc"
 	"goticketsistem/db"
 	"goticketsistem/models"
 	"log"
 	"time"
-
-	"github.com/lib/pq"
 )
 
 type TicketService struct {
-	db *db.DBManager
+	db        *db.DBManager
+	writeOpts CombinationWriteOptions
+	limits    PlacementLimits
+	currency  string
 }
 
 func NewTicketService(db *db.DBManager) *TicketService {
-	return &TicketService{db: db}
+	return &TicketService{db: db, writeOpts: DefaultCombinationWriteOptions, limits: DefaultPlacementLimits,
+		currency: models.DefaultCurrency}
 }
 
-func (ts *TicketService) CreateTicket(ticket *models.Ticket) (int, error) {
-	tx, err := ts.db.BeginTransaction()
-	if err != nil {
-		return 0, fmt.Errorf("failed to begin transaction: %v", err)
-	}
+// SetPlacementLimits menja ograničenja koja se proveravaju pre uplate tiketa.
+func (ts *TicketService) SetPlacementLimits(limits PlacementLimits) {
+	ts.limits = limits
+}
 
-	if ticket.Status == "" {
-		ticket.Status = "pending"
-	}
-	if ticket.CreatedAt.IsZero() {
-		ticket.CreatedAt = time.Now()
-	}
-	if ticket.Hits < 0 {
-		ticket.Hits = 0
-	}
-	if ticket.Misses < 0 {
-		ticket.Misses = 0
+// SetCombinationWriteOptions menja način upisa kombinacija sistemskih tiketa.
+func (ts *TicketService) SetCombinationWriteOptions(opts CombinationWriteOptions) {
+	ts.writeOpts = opts
+}
+
+// SetDefaultCurrency menja valutu tiketa koji je ne navode.
+func (ts *TicketService) SetDefaultCurrency(currency string) {
+	ts.currency = currency
+}
+
+// insertTicket upisuje tiket i njegove selekcije u transakciji koju vodi pozivalac.
+// Status, brojače, iznose i vreme uplate određuje servis, pa se vrednosti iz zahteva
+// zanemaruju: tiket uplaćen kao "lost" ne bi imao izloženost i zaobišao bi limit ishoda.
+func insertTicket(tx *sql.Tx, ticket *models.Ticket) (int, error) {
+	ticket.Status = models.StatusPending
+	ticket.CreatedAt = time.Now()
+	ticket.Hits, ticket.Misses, ticket.Pending = 0, 0, 0
+	for _, sel := range ticket.Selections {
+		if initialSelectionStatus(sel) == models.StatusPending {
+			ticket.Pending++
+		}
 	}
-	if ticket.Pending < 0 {
-		ticket.Pending = 0
+	ticket.TotalOdd, ticket.PotentialPayout, ticket.MaxPayout, ticket.MinPayout, ticket.FinalPayout = 0, 0, 0, 0, 0
+	ticket.NumCombinations = 0
+	if ticket.CombinationStorage == "" {
+		ticket.CombinationStorage = models.StorageRows
 	}
 
 	var ticketID int
 	stmt := `INSERT INTO tickets (user_id, total_stake, total_odd, potential_payout, hits, misses, pending, status, 
-             created_at, max_payout, min_payout, final_payout, num_combinations, system_combination, ticket_type)
-             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING ticket_id`
-	err = tx.QueryRow(stmt, ticket.UserID, ticket.TotalStake, ticket.TotalOdd, ticket.PotentialPayout, ticket.Hits,
+             created_at, max_payout, min_payout, final_payout, num_combinations, system_combination, ticket_type, combination_storage, payout_cap, currency)
+             VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING ticket_id`
+	payoutCap := sql.NullFloat64{Float64: ticket.PayoutCap, Valid: ticket.PayoutCap > 0}
+	err := tx.QueryRow(stmt, ticket.UserID, ticket.TotalStake, ticket.TotalOdd, ticket.PotentialPayout, ticket.Hits,
 		ticket.Misses, ticket.Pending, ticket.Status, ticket.CreatedAt, ticket.MaxPayout, ticket.MinPayout,
-		ticket.FinalPayout, ticket.NumCombinations, ticket.SystemCombination, ticket.TicketType).Scan(&ticketID)
+		ticket.FinalPayout, ticket.NumCombinations, ticket.SystemCombination, ticket.TicketType, ticket.CombinationStorage, payoutCap, ticket.Currency).Scan(&ticketID)
 	if err != nil {
-		tx.Rollback()
 		return 0, fmt.Errorf("failed to insert ticket: %v", err)
 	}
 
@@ -56,77 +71,115 @@ func (ts *TicketService) CreateTicket(ticket *models.Ticket) (int, error) {
 		stmt := `INSERT INTO selections (ticket_id, sport_type, league, home_team, away_team, event_date, 
                        market_type, selected_outcome, odd_value, stake, eid, selection_type, status, is_fixed)
                        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)`
-		_, err = tx.Exec(stmt, ticketID, sel.SportType, sel.League, sel.HomeTeam, sel.AwayTeam, sel.EventDate,
-			sel.MarketType, sel.SelectedOutcome, sel.OddValue, sel.Stake, sel.Eid, sel.SelectionType, "pending", sel.IsFixed)
+		_, err := tx.Exec(stmt, ticketID, sel.SportType, sel.League, sel.HomeTeam, sel.AwayTeam, sel.EventDate,
+			sel.MarketType, sel.SelectedOutcome, sel.OddValue, sel.Stake, sel.Eid, sel.SelectionType, initialSelectionStatus(sel), sel.IsFixed)
 		if err != nil {
-			tx.Rollback()
 			return 0, fmt.Errorf("failed to insert selection: %v", err)
 		}
 	}
+	return ticketID, nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return 0, err
+// initialSelectionStatus dozvoljava da se već otkazan meč unese kao poništena selekcija,
+// sve ostale selekcije kreću sa statusom "pending".
+func initialSelectionStatus(sel models.Selection) string {
+	if sel.Status == models.StatusVoid {
+		return models.StatusVoid
 	}
-	return ticketID, nil
+	return models.StatusPending
 }
 
 func (ts *TicketService) ProcessTicket(ticket *models.Ticket) (int, error) {
-	ticketID, err := ts.CreateTicket(ticket)
+	if ticket.TicketType == "system" && ticket.SystemCombination != "" {
+		// Čuvamo kanonski oblik sistema, a za imenovane sisteme njihovo ime (npr. "Lucky 15")
+		spec, err := calc.ParseSystem(ticket.SystemCombination)
+		if err != nil {
+			return 0, err
+		}
+		ticket.SystemCombination = spec.String()
+	}
+	if ticket.Currency == "" {
+		ticket.Currency = ts.currency
+	}
+	if !ValidCurrency(ticket.Currency) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCurrency, ticket.Currency)
+	}
+	if err := ts.limits.Check(ticket); err != nil {
+		return 0, err
+	}
+
+	// Tiket, selekcije, kombinacije, iznosi i ulog sa računa igrača se upisuju u jednoj
+	// transakciji, pa greška bilo gde poništava celu uplatu i u bazi ne ostaje tiket bez
+	// kombinacija ni ulog bez tiketa
+	tx, err := ts.db.BeginTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	ticketID, err := insertTicket(tx, ticket)
 	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := debitStake(tx, ticketID, ticket); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := ts.processTicket(tx, ticketID, ticket); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := ts.checkOutcomeLiability(tx, ticketID); err != nil {
+		tx.Rollback()
 		return 0, err
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit ticket: %v", err)
+	}
+	return ticketID, nil
+}
 
+// processTicket upisuje kombinacije i iznose već upisanog tiketa u datoj transakciji.
+func (ts *TicketService) processTicket(tx *sql.Tx, ticketID int, ticket *models.Ticket) error {
 	log.Printf("Processing ticket %d, type: %s, system_combination: %s", ticketID, ticket.TicketType, ticket.SystemCombination)
 	if ticket.TicketType == "system" && ticket.SystemCombination != "" {
 		systemService := NewSystemTicketService(ts.db)
-		return ticketID, systemService.ProcessSystemTicket(ticketID, ticket)
+		systemService.SetCombinationWriteOptions(ts.writeOpts)
+		return systemService.ProcessSystemTicket(tx, ticketID, ticket)
 	}
-	return ticketID, ts.processNormalTicket(ticketID, ticket)
+	return processNormalTicket(tx, ticketID, ticket)
 }
 
-func (ts *TicketService) processNormalTicket(ticketID int, ticket *models.Ticket) error {
-	tx, err := ts.db.BeginTransaction()
+// processNormalTicket upisuje jedinu kombinaciju normalnog tiketa i njegove iznose u
+// transakciji uplate.
+func processNormalTicket(tx *sql.Tx, ticketID int, ticket *models.Ticket) error {
+	selectionIDs, legs, err := loadTicketLegs(tx, ticketID)
 	if err != nil {
 		return err
 	}
 
-	var selectionIDs []int
-	var odds float64 = 1.0
-	rows, err := tx.Query(`SELECT selection_id, odd_value FROM selections WHERE ticket_id = $1`, ticketID)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var id int
-		var odd float64
-		if err := rows.Scan(&id, &odd); err != nil {
-			tx.Rollback()
+	result := calc.Single(legs, ticket.TotalStake)
+	result.MaxPayout = capPayout(result.MaxPayout, ticket.PayoutCap)
+	result.MinPayout = capPayout(result.MinPayout, ticket.PayoutCap)
+	writer := newCombinationWriter(tx, ticketID, selectionIDs, CombinationWriteOptions{BatchSize: 1})
+	for _, combo := range result.Combinations {
+		if err := writer.Write(combo); err != nil {
+			writer.Close()
 			return err
 		}
-		selectionIDs = append(selectionIDs, id)
-		odds *= odd
 	}
-
-	potentialWin := odds * ticket.TotalStake
-	maxPayout := potentialWin
-	minPayout := 0.0 // Za normalni tiket, min je 0 jer sve mora proći
-	numCombinations := 1
-
-	stmt := `INSERT INTO combinations (ticket_id, selection_ids, combination_odds, stake_per_combination, potential_win, status, created_at)
-             VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	if _, err := tx.Exec(stmt, ticketID, pq.Array(selectionIDs), odds, ticket.TotalStake, potentialWin, "pending", time.Now()); err != nil {
-		tx.Rollback()
+	if err := writer.Close(); err != nil {
 		return err
 	}
 
 	updateStmt := `UPDATE tickets SET total_odd = $1, potential_payout = $2, max_payout = $3, min_payout = $4, num_combinations = $5 WHERE ticket_id = $6`
-	if _, err := tx.Exec(updateStmt, odds, potentialWin, maxPayout, minPayout, numCombinations, ticketID); err != nil {
-		tx.Rollback()
+	if _, err := tx.Exec(updateStmt, result.TotalOdd, result.MaxPayout, result.MaxPayout, result.MinPayout, result.NumCombinations, ticketID); err != nil {
+		return err
+	}
+	if err := refreshTicketLiability(tx, ticketID); err != nil {
 		return err
 	}
 
-	log.Printf("Processed normal ticket %d, max_payout: %f, min_payout: %f, num_combinations: %d", ticketID, maxPayout, minPayout, numCombinations)
-	return tx.Commit()
+	log.Printf("Processed normal ticket %d, max_payout: %f, min_payout: %f, num_combinations: %d", ticketID, result.MaxPayout, result.MinPayout, result.NumCombinations)
+	return nil
 }
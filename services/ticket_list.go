@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"goticketsistem/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TicketFilter su uslovi liste tiketa; prazna polja se ne primenjuju. Opseg isplate se
+// odnosi na max_payout, a sport i liga su uslov da tiket sadrži bar jednu takvu selekciju.
+type TicketFilter struct {
+	UserID      int
+	Status      string
+	TicketType  string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	MinStake    *float64
+	MaxStake    *float64
+	MinPayout   *float64
+	MaxPayout   *float64
+	Sport       string
+	League      string
+	Cursor      string
+	Limit       int
+}
+
+// ListTickets vraća stranicu tiketa po filteru, od najnovijeg ka starijim. Kursor je
+// poslednji (created_at, ticket_id) sa prethodne stranice, pa se stranice ne pomeraju
+// kada se u međuvremenu uplate novi tiketi.
+func (ts *TicketService) ListTickets(filter TicketFilter) (*models.TicketPage, error) {
+	var conds []string
+	var args []any
+	// add dodaje uslov i redom zamenjuje svaki "?" sledećim parametrom upita
+	add := func(cond string, vals ...any) {
+		for _, v := range vals {
+			args = append(args, v)
+			cond = strings.Replace(cond, "?", "$"+strconv.Itoa(len(args)), 1)
+		}
+		conds = append(conds, cond)
+	}
+
+	if filter.UserID > 0 {
+		add("t.user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		add("t.status = ?", filter.Status)
+	}
+	if filter.TicketType != "" {
+		add("t.ticket_type = ?", filter.TicketType)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("t.created_at >= ?", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("t.created_at < ?", filter.CreatedTo)
+	}
+	if filter.MinStake != nil {
+		add("t.total_stake >= ?", *filter.MinStake)
+	}
+	if filter.MaxStake != nil {
+		add("t.total_stake <= ?", *filter.MaxStake)
+	}
+	if filter.MinPayout != nil {
+		add("t.max_payout >= ?", *filter.MinPayout)
+	}
+	if filter.MaxPayout != nil {
+		add("t.max_payout <= ?", *filter.MaxPayout)
+	}
+	if filter.Sport != "" {
+		add("EXISTS (SELECT 1 FROM selections s WHERE s.ticket_id = t.ticket_id AND s.sport_type = ?)", filter.Sport)
+	}
+	if filter.League != "" {
+		add("EXISTS (SELECT 1 FROM selections s WHERE s.ticket_id = t.ticket_id AND s.league = ?)", filter.League)
+	}
+	if filter.Cursor != "" {
+		createdAt, ticketID, err := decodeTicketCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		add("(t.created_at, t.ticket_id) < (?, ?)", createdAt, ticketID)
+	}
+
+	query := `SELECT ` + ticketColumns + ` FROM tickets t`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	// Jedan red više od tražene stranice govori da postoji sledeća
+	query += fmt.Sprintf(` ORDER BY t.created_at DESC, t.ticket_id DESC LIMIT %d`, filter.Limit+1)
+
+	rows, err := ts.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %v", err)
+	}
+	defer rows.Close()
+
+	page := &models.TicketPage{Tickets: []models.DBTicket{}}
+	for rows.Next() {
+		var t models.DBTicket
+		if err := scanTicket(rows, &t); err != nil {
+			return nil, fmt.Errorf("failed to scan ticket: %v", err)
+		}
+		page.Tickets = append(page.Tickets, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %v", err)
+	}
+
+	if len(page.Tickets) > filter.Limit {
+		page.Tickets = page.Tickets[:filter.Limit]
+		last := page.Tickets[len(page.Tickets)-1]
+		cursor := encodeTicketCursor(last.CreatedAt, last.TicketID)
+		page.NextCursor = &cursor
+	}
+	return page, nil
+}
+
+func encodeTicketCursor(createdAt time.Time, ticketID int) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.Itoa(ticketID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTicketCursor(cursor string) (time.Time, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	ticketID, err := strconv.Atoi(id)
+	if err != nil {
+		return time.Time{}, 0, ErrInvalidCursor
+	}
+	return createdAt, ticketID, nil
+}
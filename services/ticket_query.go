@@ -0,0 +1,215 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"goticketsistem/calc"
+	"goticketsistem/models"
+
+	"github.com/lib/pq"
+)
+
+var ErrTicketNotFound = errors.New("ticket not found")
+
+// ticketColumns su kolone tabele tickets koje čita scanTicket, istim redosledom.
+const ticketColumns = `t.ticket_id, t.user_id, t.total_stake, t.total_odd, t.potential_payout, t.hits, t.misses, t.pending,
+             t.status, t.created_at, t.max_payout, t.min_payout, t.final_payout, t.num_combinations, t.system_combination,
+             t.ticket_type, COALESCE(t.cashed_out_amount, 0), COALESCE(t.combination_storage, 'rows'), t.payout_cap,
+             t.currency`
+
+// rowScanner je zajednički interfejs za *sql.Row i *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTicket(row rowScanner, t *models.DBTicket) error {
+	return row.Scan(&t.TicketID, &t.UserID, &t.TotalStake, &t.TotalOdd, &t.PotentialPayout, &t.Hits, &t.Misses,
+		&t.Pending, &t.Status, &t.CreatedAt, &t.MaxPayout, &t.MinPayout, &t.FinalPayout, &t.NumCombinations,
+		&t.SystemCombination, &t.TicketType, &t.CashedOutAmount, &t.CombinationStorage, &t.PayoutCap, &t.Currency)
+}
+
+// GetTicket učitava tiket zajedno sa njegovim selekcijama i kombinacijama.
+func (ts *TicketService) GetTicket(ticketID int) (*models.TicketDetails, error) {
+	details := &models.TicketDetails{
+		Selections:   []models.DBSelection{},
+		Combinations: []models.DBCombination{},
+	}
+
+	t := &details.Ticket
+	err := scanTicket(ts.db.GetDB().QueryRow(`SELECT `+ticketColumns+` FROM tickets t WHERE t.ticket_id = $1`, ticketID), t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ticket: %v", err)
+	}
+
+	selections, err := ts.getSelections(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	details.Selections = selections
+
+	// Virtuelni tiket nema sačuvane kombinacije; dobijaju se preko ListCombinations
+	if t.CombinationStorage == models.StorageVirtual {
+		return details, nil
+	}
+	combinations, err := ts.getCombinations(ticketID)
+	if err != nil {
+		return nil, err
+	}
+	details.Combinations = combinations
+
+	return details, nil
+}
+
+func (ts *TicketService) getSelections(ticketID int) ([]models.DBSelection, error) {
+	rows, err := ts.db.Query(`SELECT selection_id, ticket_id, sport_type, league, home_team, away_team, event_date,
+             market_type, selected_outcome, odd_value, stake, eid, selection_type, status, is_fixed
+             FROM selections WHERE ticket_id = $1 ORDER BY selection_id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load selections: %v", err)
+	}
+	defer rows.Close()
+
+	selections := []models.DBSelection{}
+	for rows.Next() {
+		var s models.DBSelection
+		if err := rows.Scan(&s.ID, &s.TicketID, &s.SportType, &s.League, &s.HomeTeam, &s.AwayTeam, &s.EventDate,
+			&s.MarketType, &s.SelectedOutcome, &s.OddValue, &s.Stake, &s.Eid, &s.SelectionType, &s.Status, &s.IsFixed); err != nil {
+			return nil, fmt.Errorf("failed to scan selection: %v", err)
+		}
+		selections = append(selections, s)
+	}
+	return selections, rows.Err()
+}
+
+func (ts *TicketService) getCombinations(ticketID int) ([]models.DBCombination, error) {
+	rows, err := ts.db.Query(`SELECT combination_id, ticket_id, selection_ids, combination_odds, stake_per_combination,
+             potential_win, status, COALESCE(final_payout, 0), created_at
+             FROM combinations WHERE ticket_id = $1 ORDER BY combination_id`, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load combinations: %v", err)
+	}
+	defer rows.Close()
+
+	combinations := []models.DBCombination{}
+	for rows.Next() {
+		var c models.DBCombination
+		var ids pq.Int64Array
+		if err := rows.Scan(&c.CombinationID, &c.TicketID, &ids, &c.CombinationOdds, &c.StakePerCombination,
+			&c.PotentialWin, &c.Status, &c.FinalPayout, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan combination: %v", err)
+		}
+		c.SelectionIDs = make([]int, len(ids))
+		for i, id := range ids {
+			c.SelectionIDs[i] = int(id)
+		}
+		combinations = append(combinations, c)
+	}
+	return combinations, rows.Err()
+}
+
+// ListCombinations vraća najviše limit kombinacija tiketa počevši od pozicije from.
+// Kombinacije virtuelnih tiketa se računaju iz selekcija i sistema, sa trenutnim statusom.
+func (ts *TicketService) ListCombinations(ticketID, from, limit int) (*models.CombinationPage, error) {
+	tx, err := ts.db.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var storage string
+	var systemCombination sql.NullString
+	var totalStake float64
+	var numCombinations int
+	err = tx.QueryRow(`SELECT COALESCE(combination_storage, 'rows'), system_combination, total_stake, num_combinations
+             FROM tickets WHERE ticket_id = $1`, ticketID).Scan(&storage, &systemCombination, &totalStake, &numCombinations)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ticket: %v", err)
+	}
+
+	page := &models.CombinationPage{TicketID: ticketID, Total: numCombinations, From: from, Combinations: []models.DBCombination{}}
+	if storage == models.StorageVirtual {
+		err = listVirtualCombinations(tx, page, systemCombination.String, totalStake, limit)
+	} else {
+		err = listCombinationRows(tx, page, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if next := from + len(page.Combinations); len(page.Combinations) == limit && next < numCombinations {
+		page.NextFrom = &next
+	}
+	return page, nil
+}
+
+func listCombinationRows(tx *sql.Tx, page *models.CombinationPage, limit int) error {
+	rows, err := tx.Query(`SELECT combination_id, ticket_id, selection_ids, combination_odds, stake_per_combination,
+             potential_win, status, COALESCE(final_payout, 0), created_at
+             FROM combinations WHERE ticket_id = $1 ORDER BY combination_id OFFSET $2 LIMIT $3`, page.TicketID, page.From, limit)
+	if err != nil {
+		return fmt.Errorf("failed to load combinations: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.DBCombination
+		var ids pq.Int64Array
+		if err := rows.Scan(&c.CombinationID, &c.TicketID, &ids, &c.CombinationOdds, &c.StakePerCombination,
+			&c.PotentialWin, &c.Status, &c.FinalPayout, &c.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan combination: %v", err)
+		}
+		c.SelectionIDs = make([]int, len(ids))
+		for i, id := range ids {
+			c.SelectionIDs[i] = int(id)
+		}
+		page.Combinations = append(page.Combinations, c)
+	}
+	return rows.Err()
+}
+
+func listVirtualCombinations(tx *sql.Tx, page *models.CombinationPage, systemCombination string, totalStake float64, limit int) error {
+	spec, err := calc.ParseSystem(systemCombination)
+	if err != nil {
+		return fmt.Errorf("ticket %d: %v", page.TicketID, err)
+	}
+	selectionIDs, legs, err := loadTicketLegs(tx, page.TicketID)
+	if err != nil {
+		return err
+	}
+	plan, err := calc.NewSystemPlan(legs, spec, totalStake)
+	if err != nil {
+		return fmt.Errorf("ticket %d: %v", page.TicketID, err)
+	}
+
+	rank := page.From
+	for combo := range plan.CombinationsFrom(page.From) {
+		if len(page.Combinations) >= limit {
+			break
+		}
+		comboLegs := make([]calc.Leg, len(combo.Legs))
+		ids := make([]int, len(combo.Legs))
+		for i, leg := range combo.Legs {
+			comboLegs[i] = legs[leg]
+			ids[i] = selectionIDs[leg]
+		}
+		status, payout, potentialWin := calc.SettleCombination(comboLegs, combo.Stake)
+		page.Combinations = append(page.Combinations, models.DBCombination{
+			CombinationID:       rank,
+			TicketID:            page.TicketID,
+			SelectionIDs:        ids,
+			CombinationOdds:     combo.Odds,
+			StakePerCombination: combo.Stake,
+			PotentialWin:        potentialWin,
+			Status:              status,
+			FinalPayout:         payout,
+		})
+		rank++
+	}
+	return nil
+}
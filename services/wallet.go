@@ -0,0 +1,402 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"goticketsistem/db"
+	"goticketsistem/models"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Novčanik igrača se vodi dvojnim knjigovodstvom: svaka transakcija (wallet_transactions)
+// ima bar dve stavke (ledger_entries) čiji je zbir nula. Igrač ima račun po valuti, a kuća
+// i spoljni svet (uplate) po jedan sistemski račun po valuti. Stanje se čuva samo na
+// računu igrača; stanje sistemskog računa je zbir njegovih stavki, pa uplate tiketa ne
+// zaključavaju isti red računa kuće. Stavke uloga, isplate i povraćaja nose ticket_id,
+// a knjiže se u istoj transakciji baze u kojoj se tiket uplaćuje ili obračunava.
+
+var (
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrInvalidAmount        = errors.New("invalid amount")
+	ErrInvalidCurrency      = errors.New("invalid currency")
+	ErrDuplicateTransaction = errors.New("duplicate transaction reference")
+	ErrMissingReference     = errors.New("transaction reference is required")
+)
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// FundsError opisuje uplatu tiketa koju stanje računa ne pokriva.
+type FundsError struct {
+	Currency string
+	Balance  float64
+	Required float64
+}
+
+func (e *FundsError) Error() string {
+	return fmt.Sprintf("%v: balance %g %s, required %g", ErrInsufficientFunds, e.Balance, e.Currency, e.Required)
+}
+
+func (e *FundsError) Unwrap() error {
+	return ErrInsufficientFunds
+}
+
+type WalletService struct {
+	db       *db.DBManager
+	currency string
+}
+
+func NewWalletService(db *db.DBManager) *WalletService {
+	return &WalletService{db: db, currency: models.DefaultCurrency}
+}
+
+// SetDefaultCurrency menja valutu uplata i izvoda koji je ne navode.
+func (ws *WalletService) SetDefaultCurrency(currency string) {
+	ws.currency = currency
+}
+
+// ValidCurrency proverava da je valuta troslovni ISO 4217 kod, npr. "EUR".
+func ValidCurrency(currency string) bool {
+	return currencyCode.MatchString(currency)
+}
+
+// Balances vraća stanje svih računa igrača; igrač bez računa ima praznu listu.
+func (ws *WalletService) Balances(userID int) (*models.WalletBalances, error) {
+	rows, err := ws.db.Query(`SELECT currency, balance FROM wallet_accounts WHERE kind = $1 AND user_id = $2 ORDER BY currency`,
+		models.AccountPlayer, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load balances of user %d: %v", userID, err)
+	}
+	defer rows.Close()
+
+	result := &models.WalletBalances{UserID: userID, Balances: []models.WalletBalance{}}
+	for rows.Next() {
+		var b models.WalletBalance
+		if err := rows.Scan(&b.Currency, &b.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %v", err)
+		}
+		result.Balances = append(result.Balances, b)
+	}
+	return result, rows.Err()
+}
+
+// Statement vraća stranicu izvoda računa igrača u datoj valuti, od najnovije stavke.
+// Kursor je entry_id poslednje stavke sa prethodne stranice.
+func (ws *WalletService) Statement(userID int, currency, cursor string, limit int) (*models.WalletStatement, error) {
+	if currency == "" {
+		currency = ws.currency
+	}
+	if !ValidCurrency(currency) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCurrency, currency)
+	}
+	statement := &models.WalletStatement{UserID: userID, Currency: currency, Entries: []models.LedgerEntry{}}
+
+	before := int64(math.MaxInt64)
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, ErrInvalidCursor
+		}
+		before = id
+	}
+
+	var accountID int64
+	err := ws.db.GetDB().QueryRow(`SELECT account_id, balance FROM wallet_accounts WHERE kind = $1 AND user_id = $2 AND currency = $3`,
+		models.AccountPlayer, userID, currency).Scan(&accountID, &statement.Balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return statement, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load account of user %d: %v", userID, err)
+	}
+
+	// Jedan red više od tražene stranice govori da postoji sledeća
+	rows, err := ws.db.Query(`SELECT e.entry_id, e.transaction_id, wt.kind, e.ticket_id, e.amount, COALESCE(e.balance_after, 0), e.created_at
+             FROM ledger_entries e JOIN wallet_transactions wt ON wt.transaction_id = e.transaction_id
+             WHERE e.account_id = $1 AND e.entry_id < $2 ORDER BY e.entry_id DESC LIMIT $3`, accountID, before, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load statement of user %d: %v", userID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e models.LedgerEntry
+		var ticketID sql.NullInt64
+		if err := rows.Scan(&e.EntryID, &e.TransactionID, &e.Kind, &ticketID, &e.Amount, &e.BalanceAfter, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan ledger entry: %v", err)
+		}
+		if ticketID.Valid {
+			id := int(ticketID.Int64)
+			e.TicketID = &id
+		}
+		statement.Entries = append(statement.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load statement of user %d: %v", userID, err)
+	}
+
+	if len(statement.Entries) > limit {
+		statement.Entries = statement.Entries[:limit]
+		next := strconv.FormatInt(statement.Entries[limit-1].EntryID, 10)
+		statement.NextCursor = &next
+	}
+	return statement, nil
+}
+
+// Deposit knjiži uplatu sa spoljnog računa na račun igrača i otvara račun ako ne postoji.
+func (ws *WalletService) Deposit(userID int, req models.DepositRequest) (*models.DepositResponse, error) {
+	amount := req.Amount
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: deposit must be positive", ErrInvalidAmount)
+	}
+	if strings.TrimSpace(req.Reference) == "" {
+		return nil, fmt.Errorf("%w: deposit needs the provider's payment reference", ErrMissingReference)
+	}
+	if req.Currency == "" {
+		req.Currency = ws.currency
+	}
+	if !ValidCurrency(req.Currency) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCurrency, req.Currency)
+	}
+
+	tx, err := ws.db.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	player, _, err := lockPlayerAccount(tx, userID, req.Currency, true)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	external, err := systemAccount(tx, models.AccountExternal, req.Currency)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	transactionID, balances, err := postTransaction(tx, models.WalletDeposit, 0, req.Reference,
+		[]posting{{accountID: player, amount: amount, player: true}, {accountID: external, amount: -amount}})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Deposited %f %s to user %d (transaction %d)", amount, req.Currency, userID, transactionID)
+	return &models.DepositResponse{TransactionID: transactionID, Currency: req.Currency, Balance: balances[player]}, nil
+}
+
+// debitStake skida ulog tiketa sa računa igrača u transakciji uplate. Račun se zaključava
+// pre provere stanja, pa dve istovremene uplate ne mogu potrošiti isti novac.
+func debitStake(tx *sql.Tx, ticketID int, ticket *models.Ticket) error {
+	stake := ticket.TotalStake
+	player, balance, err := lockPlayerAccount(tx, ticket.UserID, ticket.Currency, false)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &FundsError{Currency: ticket.Currency, Balance: 0, Required: stake}
+	}
+	if err != nil {
+		return err
+	}
+	if balance < stake {
+		return &FundsError{Currency: ticket.Currency, Balance: balance, Required: stake}
+	}
+
+	house, err := systemAccount(tx, models.AccountHouse, ticket.Currency)
+	if err != nil {
+		return err
+	}
+	_, _, err = postTransaction(tx, models.WalletStake, ticketID, "",
+		[]posting{{accountID: player, amount: -stake, player: true}, {accountID: house, amount: stake}})
+	return err
+}
+
+// creditTicketPayout usklađuje knjiženja tiketa sa njegovom konačnom isplatom: igraču se
+// knjiži razlika između isplate i već knjiženih isplata i povraćaja tog tiketa. Ponovni
+// obračun (npr. ispravljen rezultat) tako knjiži samo promenu, a tiket vraćen na čekanje
+// poništava ranije knjiženu isplatu. Poništen tiket se knjiži kao povraćaj.
+func creditTicketPayout(tx *sql.Tx, ticketID int, status string, finalPayout float64) error {
+	player, house, ok, err := ticketAccounts(tx, ticketID)
+	if err != nil || !ok {
+		return err
+	}
+
+	var credited float64
+	if err := tx.QueryRow(`SELECT COALESCE(SUM(e.amount), 0) FROM ledger_entries e
+             JOIN wallet_transactions wt ON wt.transaction_id = e.transaction_id
+             WHERE e.ticket_id = $1 AND e.account_id = $2 AND wt.kind IN ($3, $4)`,
+		ticketID, player, models.WalletPayout, models.WalletRefund).Scan(&credited); err != nil {
+		return fmt.Errorf("failed to load payouts of ticket %d: %v", ticketID, err)
+	}
+	if status == models.StatusPending {
+		finalPayout = 0
+	}
+	// Isplata se zaokružuje naniže na cent, a razlika na najbliži cent, da ponovni
+	// obračun sa istom isplatom ne bi knjižio ostatak od zaokruživanja
+	delta := math.Round((roundMoney(finalPayout)-credited)*100) / 100
+	if delta == 0 {
+		return nil
+	}
+
+	kind := models.WalletPayout
+	if status == models.StatusVoid {
+		kind = models.WalletRefund
+	}
+	_, balances, err := postTransaction(tx, kind, ticketID, "",
+		[]posting{{accountID: player, amount: delta, player: true}, {accountID: house, amount: -delta}})
+	if err != nil {
+		return err
+	}
+	if delta < 0 {
+		log.Printf("Reversed %f of ticket %d payout after resettlement, balance %f", -delta, ticketID, balances[player])
+	}
+	return nil
+}
+
+// creditCashOut knjiži isplatu pre kraja na račun igrača; quoteID je referenca transakcije.
+func creditCashOut(tx *sql.Tx, ticketID int, quoteID string, amount float64) error {
+	player, house, ok, err := ticketAccounts(tx, ticketID)
+	if err != nil || !ok {
+		return err
+	}
+	amount = roundMoney(amount)
+	if amount <= 0 {
+		return nil
+	}
+	_, _, err = postTransaction(tx, models.WalletCashOut, ticketID, "cashout:"+quoteID,
+		[]posting{{accountID: player, amount: amount, player: true}, {accountID: house, amount: -amount}})
+	return err
+}
+
+// ticketAccounts vraća zaključan račun igrača i račun kuće za tiket. Tiket čiji ulog nije
+// knjižen (uplaćen pre uvođenja novčanika) nema knjiženja, pa je ok false.
+func ticketAccounts(tx *sql.Tx, ticketID int) (int64, int64, bool, error) {
+	var userID int
+	var currency string
+	var staked bool
+	if err := tx.QueryRow(`SELECT user_id, currency, EXISTS (SELECT 1 FROM wallet_transactions WHERE ticket_id = $1 AND kind = $2)
+             FROM tickets WHERE ticket_id = $1`, ticketID, models.WalletStake).Scan(&userID, &currency, &staked); err != nil {
+		return 0, 0, false, fmt.Errorf("failed to load ticket %d: %v", ticketID, err)
+	}
+	if !staked {
+		log.Printf("Ticket %d has no stake in the wallet ledger, skipping wallet posting", ticketID)
+		return 0, 0, false, nil
+	}
+
+	player, _, err := lockPlayerAccount(tx, userID, currency, false)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to load account of ticket %d: %v", ticketID, err)
+	}
+	house, err := systemAccount(tx, models.AccountHouse, currency)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return player, house, true, nil
+}
+
+// lockTicketAccounts zaključava račune igrača svih datih tiketa redom po account_id.
+func lockTicketAccounts(tx *sql.Tx, ticketIDs []int) error {
+	rows, err := tx.Query(`SELECT account_id FROM wallet_accounts
+             WHERE kind = $1 AND (user_id, currency) IN (SELECT user_id, currency FROM tickets WHERE ticket_id = ANY($2))
+             ORDER BY account_id FOR UPDATE`, models.AccountPlayer, pq.Array(ticketIDs))
+	if err != nil {
+		return fmt.Errorf("failed to lock accounts: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// lockPlayerAccount zaključava račun igrača i vraća njegovo stanje; create otvara račun
+// ako ne postoji, a inače se vraća sql.ErrNoRows.
+func lockPlayerAccount(tx *sql.Tx, userID int, currency string, create bool) (int64, float64, error) {
+	if create {
+		if _, err := tx.Exec(`INSERT INTO wallet_accounts (kind, user_id, currency) VALUES ($1, $2, $3)
+                 ON CONFLICT (user_id, currency) WHERE kind = 'player' DO NOTHING`, models.AccountPlayer, userID, currency); err != nil {
+			return 0, 0, fmt.Errorf("failed to open account of user %d: %v", userID, err)
+		}
+	}
+	var accountID int64
+	var balance float64
+	err := tx.QueryRow(`SELECT account_id, balance FROM wallet_accounts WHERE kind = $1 AND user_id = $2 AND currency = $3 FOR UPDATE`,
+		models.AccountPlayer, userID, currency).Scan(&accountID, &balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, err
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to lock account of user %d: %v", userID, err)
+	}
+	return accountID, balance, nil
+}
+
+// systemAccount vraća račun kuće ili spoljni račun za valutu i otvara ga pri prvoj upotrebi.
+func systemAccount(tx *sql.Tx, kind, currency string) (int64, error) {
+	var accountID int64
+	err := tx.QueryRow(`SELECT account_id FROM wallet_accounts WHERE kind = $1 AND currency = $2`, kind, currency).Scan(&accountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = tx.QueryRow(`INSERT INTO wallet_accounts (kind, currency) VALUES ($1, $2)
+                 ON CONFLICT (kind, currency) WHERE kind <> 'player' DO UPDATE SET kind = EXCLUDED.kind
+                 RETURNING account_id`, kind, currency).Scan(&accountID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to load %s account for %s: %v", kind, currency, err)
+	}
+	return accountID, nil
+}
+
+// posting je jedna stavka transakcije; player označava račun igrača, čije se stanje ažurira.
+type posting struct {
+	accountID int64
+	amount    float64
+	player    bool
+}
+
+// postTransaction upisuje transakciju i njene stavke i vraća stanja računa igrača posle
+// knjiženja. Zbir stavki mora biti nula; ponovljena referenca vraća ErrDuplicateTransaction.
+func postTransaction(tx *sql.Tx, kind string, ticketID int, reference string, postings []posting) (int64, map[int64]float64, error) {
+	var sum float64
+	for _, p := range postings {
+		sum += p.amount
+	}
+	if math.Abs(sum) > 1e-9 {
+		return 0, nil, fmt.Errorf("unbalanced %s transaction: entries sum to %g", kind, sum)
+	}
+
+	now := time.Now()
+	ticket := sql.NullInt64{Int64: int64(ticketID), Valid: ticketID > 0}
+	ref := sql.NullString{String: reference, Valid: reference != ""}
+	var transactionID int64
+	err := tx.QueryRow(`INSERT INTO wallet_transactions (kind, ticket_id, reference, created_at) VALUES ($1, $2, $3, $4)
+             ON CONFLICT (reference) DO NOTHING RETURNING transaction_id`, kind, ticket, ref, now).Scan(&transactionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, fmt.Errorf("%w: %s", ErrDuplicateTransaction, reference)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to record %s transaction: %v", kind, err)
+	}
+
+	balances := make(map[int64]float64)
+	for _, p := range postings {
+		var balanceAfter sql.NullFloat64
+		if p.player {
+			if err := tx.QueryRow(`UPDATE wallet_accounts SET balance = balance + $1 WHERE account_id = $2 RETURNING balance`,
+				p.amount, p.accountID).Scan(&balanceAfter.Float64); err != nil {
+				return 0, nil, fmt.Errorf("failed to update account %d: %v", p.accountID, err)
+			}
+			balanceAfter.Valid = true
+			balances[p.accountID] = balanceAfter.Float64
+		}
+		if _, err := tx.Exec(`INSERT INTO ledger_entries (transaction_id, account_id, ticket_id, amount, balance_after, created_at)
+                 VALUES ($1, $2, $3, $4, $5, $6)`, transactionID, p.accountID, ticket, p.amount, balanceAfter, now); err != nil {
+			return 0, nil, fmt.Errorf("failed to record %s entry for account %d: %v", kind, p.accountID, err)
+		}
+	}
+	return transactionID, balances, nil
+}
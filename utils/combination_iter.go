@@ -0,0 +1,73 @@
+package utils
+
+import "iter"
+
+// Combinations lenjo vraća sve k-člane podskupove pozicija 0..n-1 u leksikografskom redosledu.
+func Combinations(n, k int) iter.Seq[[]int] {
+	return CombinationsFrom(n, k, 0)
+}
+
+// CombinationsFrom lenjo vraća k-člane podskupove pozicija 0..n-1 u leksikografskom
+// redosledu, počevši od kombinacije sa datim rangom (0 je prva). Memorija ne zavisi od
+// broja kombinacija; svaka vraćena kombinacija je nov slice koji pozivalac može da zadrži.
+func CombinationsFrom(n, k, rank int) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		if k < 0 || k > n || rank < 0 || rank >= Binom(n, k) {
+			return
+		}
+		current := UnrankCombination(n, k, rank)
+		for {
+			combination := make([]int, k)
+			copy(combination, current)
+			if !yield(combination) {
+				return
+			}
+
+			// Sledeća kombinacija: najdesnija pozicija koja još može da se poveća
+			i := k - 1
+			for i >= 0 && current[i] == n-k+i {
+				i--
+			}
+			if i < 0 {
+				return
+			}
+			current[i]++
+			for j := i + 1; j < k; j++ {
+				current[j] = current[j-1] + 1
+			}
+		}
+	}
+}
+
+// UnrankCombination vraća kombinaciju sa datim rangom u leksikografskom redosledu
+// k-članih podskupova pozicija 0..n-1.
+func UnrankCombination(n, k, rank int) []int {
+	combination := make([]int, k)
+	next := 0
+	for i := 0; i < k; i++ {
+		for v := next; v < n; v++ {
+			count := Binom(n-v-1, k-i-1)
+			if rank < count {
+				combination[i] = v
+				next = v + 1
+				break
+			}
+			rank -= count
+		}
+	}
+	return combination
+}
+
+// RankCombination vraća leksikografski rang rastuće sortirane kombinacije pozicija 0..n-1.
+func RankCombination(n int, combination []int) int {
+	k := len(combination)
+	rank := 0
+	next := 0
+	for i, c := range combination {
+		for v := next; v < c; v++ {
+			rank += Binom(n-v-1, k-i-1)
+		}
+		next = c + 1
+	}
+	return rank
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// lexCombinations rekurzivno nabraja k-člane podskupove pozicija 0..n-1 u leksikografskom
+// redosledu, nezavisno od iteratora koji se testira.
+func lexCombinations(n, k int) [][]int {
+	var out [][]int
+	var walk func(start int, prefix []int)
+	walk = func(start int, prefix []int) {
+		if len(prefix) == k {
+			out = append(out, slices.Clone(prefix))
+			return
+		}
+		for v := start; v < n; v++ {
+			walk(v+1, append(prefix, v))
+		}
+	}
+	walk(0, nil)
+	return out
+}
+
+func collect(n, k, rank int) [][]int {
+	var out [][]int
+	for c := range CombinationsFrom(n, k, rank) {
+		out = append(out, c)
+	}
+	return out
+}
+
+func TestCombinationRanks(t *testing.T) {
+	for n := 0; n <= 8; n++ {
+		for k := 0; k <= n; k++ {
+			want := lexCombinations(n, k)
+			all := collect(n, k, 0)
+			if !slices.EqualFunc(all, want, slices.Equal) {
+				t.Fatalf("Combinations(%d, %d) = %v, want %v", n, k, all, want)
+			}
+			if len(all) != Binom(n, k) {
+				t.Errorf("Combinations(%d, %d) yields %d combinations, Binom = %d", n, k, len(all), Binom(n, k))
+			}
+
+			for rank, c := range want {
+				if got := RankCombination(n, c); got != rank {
+					t.Errorf("RankCombination(%d, %v) = %d, want %d", n, c, got, rank)
+				}
+				if got := UnrankCombination(n, k, rank); !slices.Equal(got, c) {
+					t.Errorf("UnrankCombination(%d, %d, %d) = %v, want %v", n, k, rank, got, c)
+				}
+				if got := collect(n, k, rank); !slices.EqualFunc(got, want[rank:], slices.Equal) {
+					t.Errorf("CombinationsFrom(%d, %d, %d) = %v, want %v", n, k, rank, got, want[rank:])
+				}
+			}
+		}
+	}
+}
+
+func TestCombinationsFromOutOfRange(t *testing.T) {
+	tests := []struct{ n, k, rank int }{
+		{5, 2, -1},
+		{5, 2, 10},
+		{5, 6, 0},
+		{5, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d from %d", tt.k, tt.n, tt.rank), func(t *testing.T) {
+			if got := collect(tt.n, tt.k, tt.rank); len(got) != 0 {
+				t.Errorf("CombinationsFrom(%d, %d, %d) = %v, want nothing", tt.n, tt.k, tt.rank, got)
+			}
+		})
+	}
+}